@@ -0,0 +1,239 @@
+// Package admin provides an http.Handler to inspect and change loggers at runtime
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+)
+
+// Module is the information of a logger
+type Module struct {
+	Name      string `json:"name"`
+	Level     string `json:"level"`
+	Inherited bool   `json:"inherited"`
+	Formatter string `json:"formatter"`
+	Writer    string `json:"writer"`
+	Filters   int    `json:"filters"`
+	// RestoreAt is the time when the previous level will be restored, set when level changed with ttl
+	RestoreAt *time.Time `json:"restore_at,omitempty"`
+}
+
+// LevelRequest is the request to change the level of a module
+type LevelRequest struct {
+	Level string `json:"level"`
+	// TTL is a duration such as 10m, the previous level will be restored after it
+	TTL string `json:"ttl"`
+}
+
+// restore is a pending restore for module level
+type restore struct {
+	timer    *time.Timer
+	at       time.Time
+	previous level.Level
+	own      bool
+}
+
+// Handler is a http.Handler to inspect and change loggers
+//
+//	GET  /                 list all modules
+//	GET  /modules/{name}   show a module
+//	PUT  /modules/{name}   change the level of a module, POST is also accepted
+//	POST /reopen           reopen all loggers
+//
+// Use http.StripPrefix to mount it to a sub path
+type Handler struct {
+	restores map[string]*restore
+	lock     sync.Mutex
+}
+
+// NewHandler create a new Handler
+func NewHandler() *Handler {
+	return &Handler{restores: make(map[string]*restore)}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case path == "" || path == "modules":
+		if r.Method != http.MethodGet {
+			h.methodNotAllowed(w, http.MethodGet)
+			return
+		}
+
+		h.writeJSON(w, http.StatusOK, h.Modules())
+	case strings.HasPrefix(path, "modules/"):
+		name := strings.TrimPrefix(path, "modules/")
+		switch r.Method {
+		case http.MethodGet:
+			if _, ok := log.All()[name]; !ok {
+				h.writeError(w, http.StatusNotFound, fmt.Errorf("module %s not found", name))
+				return
+			}
+
+			h.writeJSON(w, http.StatusOK, h.module(log.Module(name)))
+		case http.MethodPut, http.MethodPost:
+			h.changeLevel(w, r, name)
+		default:
+			h.methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodPost)
+		}
+	case path == "reopen":
+		if r.Method != http.MethodPost {
+			h.methodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		results := make(map[string]string)
+		status := http.StatusOK
+		for name, err := range log.ReOpenAll() {
+			if err != nil {
+				results[name] = err.Error()
+				status = http.StatusInternalServerError
+			} else {
+				results[name] = "ok"
+			}
+		}
+
+		h.writeJSON(w, status, results)
+	default:
+		h.writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+// Modules return all modules sorted by name
+func (h *Handler) Modules() []Module {
+	all := log.All()
+	modules := make([]Module, 0, len(all))
+	for _, l := range all {
+		modules = append(modules, h.module(l))
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+
+	return modules
+}
+
+// SetLevel change the level of module, the previous level will be restored after ttl if ttl > 0
+func (h *Handler) SetLevel(name string, le level.Level, ttl time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	logger := log.Module(name)
+
+	// keep the level before the first temporary change, so that it will be restored finally
+	pending, hasPending := h.restores[name]
+	if hasPending {
+		pending.timer.Stop()
+		delete(h.restores, name)
+	}
+
+	if ttl > 0 {
+		rs := &restore{at: time.Now().Add(ttl)}
+		if hasPending {
+			rs.previous, rs.own = pending.previous, pending.own
+		} else {
+			rs.previous, rs.own = logger.GetOwnLevel()
+		}
+
+		rs.timer = time.AfterFunc(ttl, func() { h.restore(name, rs) })
+		h.restores[name] = rs
+	}
+
+	logger.LogLevel(le)
+}
+
+func (h *Handler) restore(name string, rs *restore) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// the restore has been replaced by another change
+	if h.restores[name] != rs {
+		return
+	}
+
+	delete(h.restores, name)
+
+	if rs.own {
+		log.Module(name).LogLevel(rs.previous)
+	} else {
+		log.Module(name).ClearLogLevel()
+	}
+}
+
+func (h *Handler) changeLevel(w http.ResponseWriter, r *http.Request, name string) {
+	var req LevelRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	} else {
+		req.Level, req.TTL = r.FormValue("level"), r.FormValue("ttl")
+	}
+
+	le := level.GetLevelByName(req.Level)
+	if le == 0 {
+		h.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid level: %s", req.Level))
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+			h.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl: %s", req.TTL))
+			return
+		}
+	}
+
+	h.SetLevel(name, le, ttl)
+	h.writeJSON(w, http.StatusOK, h.module(log.Module(name)))
+}
+
+func (h *Handler) module(l *log.AsteriaLogger) Module {
+	_, own := l.GetOwnLevel()
+	m := Module{
+		Name:      l.GetModuleName(),
+		Level:     l.GetLevel().GetLevelName(),
+		Inherited: !own,
+		Formatter: fmt.Sprintf("%T", l.GetFormatter()),
+		Writer:    fmt.Sprintf("%T", l.GetWriter()),
+		Filters:   len(l.Filters()),
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if rs, ok := h.restores[m.Name]; ok {
+		at := rs.at
+		m.RestoreAt = &at
+	}
+
+	return m
+}
+
+func (h *Handler) methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	h.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, err error) {
+	h.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
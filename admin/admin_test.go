@@ -0,0 +1,163 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/admin"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockWriter struct {
+	ReOpenCount int
+}
+
+func (w *MockWriter) Write(le level.Level, module string, message string) error {
+	return nil
+}
+
+func (w *MockWriter) ReOpen() error {
+	w.ReOpenCount++
+	return nil
+}
+
+func (w *MockWriter) Close() error {
+	return nil
+}
+
+func request(t *testing.T, handler http.Handler, method, path string, body string, contentType string) (int, []byte) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code, rec.Body.Bytes()
+}
+
+func TestHandler_Modules(t *testing.T) {
+	log.Reset()
+	log.DefaultLogWriter(&MockWriter{})
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+	log.DefaultLogLevel(level.Info)
+
+	log.Module("asteria.user").LogLevel(level.Debug)
+	log.Module("asteria.user.jobs")
+
+	handler := http.StripPrefix("/admin/log", admin.NewHandler())
+
+	code, body := request(t, handler, http.MethodGet, "/admin/log/", "", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	var modules []admin.Module
+	assert.NoError(t, json.Unmarshal(body, &modules))
+	assert.Len(t, modules, 2)
+	assert.Equal(t, admin.Module{
+		Name:      "asteria.user.jobs",
+		Level:     "DEBUG",
+		Inherited: true,
+		Formatter: "*formatter.JSONFormatter",
+		Writer:    "*admin_test.MockWriter",
+	}, modules[1])
+	assert.False(t, modules[0].Inherited)
+
+	code, _ = request(t, handler, http.MethodGet, "/admin/log/modules/not-exist", "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = request(t, handler, http.MethodDelete, "/admin/log/modules", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestHandler_ChangeLevel(t *testing.T) {
+	log.Reset()
+	log.DefaultLogWriter(&MockWriter{})
+	log.DefaultLogLevel(level.Info)
+
+	handler := admin.NewHandler()
+
+	code, body := request(t, handler, http.MethodPut, "/modules/asteria.user", `{"level": "debug"}`, "application/json")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, log.Module("asteria.user").DebugEnabled())
+
+	var module admin.Module
+	assert.NoError(t, json.Unmarshal(body, &module))
+	assert.Equal(t, "DEBUG", module.Level)
+	assert.Nil(t, module.RestoreAt)
+
+	code, _ = request(t, handler, http.MethodPost, "/modules/asteria.user", url.Values{"level": {"unknown"}}.Encode(), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = request(t, handler, http.MethodPost, "/modules/asteria.user?level=error&ttl=1x", "", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.True(t, log.Module("asteria.user").DebugEnabled())
+}
+
+func TestHandler_ChangeLevelWithTTL(t *testing.T) {
+	log.Reset()
+	log.DefaultLogWriter(&MockWriter{})
+	log.DefaultLogLevel(level.Info)
+
+	handler := admin.NewHandler()
+
+	// the level is inherited before change, it will be cleared when restoring
+	code, body := request(t, handler, http.MethodPost, "/modules/asteria.user?level=debug&ttl=50ms", "", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, log.Module("asteria.user").DebugEnabled())
+
+	var module admin.Module
+	assert.NoError(t, json.Unmarshal(body, &module))
+	assert.NotNil(t, module.RestoreAt)
+
+	// change again before restored, the original level will be restored
+	request(t, handler, http.MethodPost, "/modules/asteria.user?level=error&ttl=50ms", "", "")
+	assert.False(t, log.Module("asteria.user").InfoEnabled())
+
+	for i := 0; i < 100 && !log.Module("asteria.user").InfoEnabled(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.True(t, log.Module("asteria.user").InfoEnabled())
+	assert.False(t, log.Module("asteria.user").DebugEnabled())
+
+	_, own := log.Module("asteria.user").GetOwnLevel()
+	assert.False(t, own)
+
+	// the level set for module will be restored
+	log.Module("asteria.order").LogLevel(level.Warning)
+	handler.SetLevel("asteria.order", level.Debug, 20*time.Millisecond)
+	for i := 0; i < 100 && log.Module("asteria.order").DebugEnabled(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	le, own := log.Module("asteria.order").GetOwnLevel()
+	assert.True(t, own)
+	assert.Equal(t, level.Warning, le)
+}
+
+func TestHandler_ReOpen(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogWriter(mockWriter)
+	log.Module("asteria.user")
+
+	handler := admin.NewHandler()
+
+	code, _ := request(t, handler, http.MethodGet, "/reopen", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	code, body := request(t, handler, http.MethodPost, "/reopen", "", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"asteria.user": "ok"}`, string(body))
+	assert.Equal(t, 1, mockWriter.ReOpenCount)
+}
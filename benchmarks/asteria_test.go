@@ -73,6 +73,47 @@ func BenchmarkAsteriaWithModule(b *testing.B) {
 	}
 }
 
+// BenchmarkAsteriaTyped is not allocation free, it takes about 5 allocs/op (the typed logger, the message and time text, the filter chain)
+func BenchmarkAsteriaTyped(b *testing.B) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Typed(
+			log.Int("int", 12),
+			log.String("string", "Hello"),
+			log.Float64("float", 33.4),
+		).Debug("Hello, world")
+	}
+}
+
+func BenchmarkAsteriaTypedWithModule(b *testing.B) {
+	var logger = log.Module("test").Typed(
+		log.Int("int", 12),
+		log.String("string", "Hello"),
+		log.Float64("float", 33.4),
+	)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("Hello, world")
+	}
+}
+
+func BenchmarkAsteriaTypedWithNested(b *testing.B) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Typed(
+			log.Int("int", 12),
+			log.String("string", "Hello"),
+			log.Float64("float", 33.4),
+			log.Any("nested", log.Fields{
+				"int":    12,
+				"string": "Hello",
+				"float":  33.4,
+			}),
+		).Debug("Hello, world")
+	}
+}
+
 func BenchmarkAsteriaWithJsonFormatter(b *testing.B) {
 	log.SetFormatter(formatter.NewJSONWithTimeFormatter())
 
@@ -0,0 +1,456 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/asteria/writer"
+)
+
+var applyLock sync.Mutex
+
+// Applied hold the writers created by Apply, they should be closed when the config is replaced
+type Applied struct {
+	Writers map[string]writer.Writer
+
+	// cancel stop the background goroutines of writers, such as cleaning expired files
+	cancel context.CancelFunc
+}
+
+// Close all writers created by Apply, buffered messages will be flushed
+func (applied *Applied) Close() map[string]error {
+	errors := make(map[string]error, len(applied.Writers))
+	for name, w := range applied.Writers {
+		errors[name] = w.Close()
+	}
+
+	if applied.cancel != nil {
+		applied.cancel()
+	}
+
+	return errors
+}
+
+// Apply the config to loggers, all writers and formatters are built before applying,
+// so nothing will be changed if the config is invalid
+//
+// Settings not specified in default section keep the current DefaultConfig values
+func (conf *Config) Apply(ctx context.Context) (*Applied, error) {
+	return conf.apply(ctx, log.GetDefaultConfig())
+}
+
+// apply the config, the settings not specified in default section fallback to base
+func (conf *Config) apply(ctx context.Context, base log.DefaultConfig) (*Applied, error) {
+	// the writers live until the config is replaced, not as long as ctx
+	ctx, cancel := context.WithCancel(ctx)
+	b := &builder{
+		ctx:        ctx,
+		conf:       conf,
+		writers:    make(map[string]writer.Writer),
+		formatters: make(map[string]formatter.Formatter),
+		building:   make(map[string]bool),
+	}
+
+	applied := &Applied{Writers: b.writers, cancel: cancel}
+	modules, err := b.build()
+	if err != nil {
+		applied.Close()
+		return nil, err
+	}
+
+	applyLock.Lock()
+	defer applyLock.Unlock()
+
+	defaults := modules["default"]
+	if defaults.level == 0 {
+		defaults.level = base.LogLevel
+	}
+	if defaults.formatter == nil {
+		defaults.formatter = base.LogFormatter
+	}
+	if defaults.writer == nil {
+		defaults.writer = base.LogWriter
+	}
+	if defaults.fileLine == nil {
+		defaults.fileLine = &base.WithFileLine
+	}
+
+	// modules not listed in config are reset to default settings, loggers switch to the new settings at once
+	settings := make(map[string]log.ModuleSettings, len(conf.Modules))
+	for name := range conf.Modules {
+		m := modules["module:"+name]
+		settings[name] = log.ModuleSettings{Level: m.level, Formatter: m.formatter, Writer: m.writer, FileLine: m.fileLine}
+	}
+
+	log.Configure(log.ModuleSettings{
+		Level:     defaults.level,
+		Formatter: defaults.formatter,
+		Writer:    defaults.writer,
+		FileLine:  defaults.fileLine,
+	}, settings)
+
+	return applied, nil
+}
+
+type moduleSettings struct {
+	level     level.Level
+	formatter formatter.Formatter
+	writer    writer.Writer
+	fileLine  *bool
+}
+
+type builder struct {
+	ctx        context.Context
+	conf       *Config
+	writers    map[string]writer.Writer
+	formatters map[string]formatter.Formatter
+	building   map[string]bool
+}
+
+func (b *builder) build() (map[string]moduleSettings, error) {
+	for name := range b.conf.Formatters {
+		if _, err := b.formatter(name); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range b.conf.Writers {
+		if _, err := b.writer(name); err != nil {
+			return nil, err
+		}
+	}
+
+	modules := make(map[string]moduleSettings, len(b.conf.Modules)+1)
+
+	m, err := b.module(b.conf.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default: %s", err)
+	}
+	modules["default"] = m
+
+	for name, mc := range b.conf.Modules {
+		m, err := b.module(mc)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %s", name, err)
+		}
+		modules["module:"+name] = m
+	}
+
+	return modules, nil
+}
+
+func (b *builder) module(mc ModuleConfig) (moduleSettings, error) {
+	m := moduleSettings{fileLine: mc.FileLine}
+
+	if mc.Level != "" {
+		le, err := parseLevel(mc.Level)
+		if err != nil {
+			return m, err
+		}
+		m.level = le
+	}
+
+	if mc.Formatter != "" {
+		f, err := b.formatter(mc.Formatter)
+		if err != nil {
+			return m, err
+		}
+		m.formatter = f
+	}
+
+	if mc.Writer != "" {
+		w, err := b.writer(mc.Writer)
+		if err != nil {
+			return m, err
+		}
+		m.writer = w
+	}
+
+	return m, nil
+}
+
+func (b *builder) formatter(name string) (formatter.Formatter, error) {
+	if f, ok := b.formatters[name]; ok {
+		return f, nil
+	}
+
+	fc, ok := b.conf.Formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("formatter %s not defined", name)
+	}
+
+	var f formatter.Formatter
+	switch fc.Type {
+	case "", "default":
+		f = formatter.NewDefaultFormatter(fc.Colorful)
+	case "json":
+		jf, err := newJSONFormatter(fc)
+		if err != nil {
+			return nil, fmt.Errorf("formatter %s: %v", name, err)
+		}
+		f = jf
+	case "json_with_time":
+		f = formatter.NewJSONWithTimeFormatter()
+	case "logfmt":
+		f = formatter.NewLogfmtFormatter()
+	case "template":
+		tf, err := formatter.NewTemplateFormatter(fc.Template)
+		if err != nil {
+			return nil, fmt.Errorf("formatter %s: %v", name, err)
+		}
+		f = tf
+	case "gelf":
+		gelf := formatter.NewGELFFormatter()
+		if fc.Host != "" {
+			gelf.Host(fc.Host)
+		}
+		f = gelf
+	case "rfc5424":
+		facility, ok := facilities[fc.Facility]
+		if !ok {
+			return nil, fmt.Errorf("formatter %s: unsupported facility %s", name, fc.Facility)
+		}
+		rfc5424 := formatter.NewRFC5424Formatter(facility)
+		if fc.Host != "" {
+			rfc5424.Hostname(fc.Host)
+		}
+		f = rfc5424
+	case "ecs":
+		ecs := formatter.NewECSFormatter().Labels(fc.Labels)
+		if fc.Namespace != nil {
+			ecs.Namespace(*fc.Namespace)
+		}
+		f = ecs
+	default:
+		return nil, fmt.Errorf("formatter %s: unsupported type %s", name, fc.Type)
+	}
+
+	b.formatters[name] = f
+	return f, nil
+}
+
+func newJSONFormatter(fc FormatterConfig) (*formatter.JSONFormatter, error) {
+	f := formatter.NewJSONFormatter().FlattenFields(fc.Flatten)
+
+	switch strings.ToLower(fc.TimeFormat) {
+	case "", "rfc3339":
+	case "rfc3339nano":
+		f.TimeFormat(formatter.JSONTimeRFC3339Nano)
+	case "unix":
+		f.TimeFormat(formatter.JSONTimeUnix)
+	case "unixmilli":
+		f.TimeFormat(formatter.JSONTimeUnixMilli)
+	default:
+		f.TimeLayout(fc.TimeFormat)
+	}
+
+	if fc.GlobalPrefix != nil {
+		f.GlobalFieldPrefix(*fc.GlobalPrefix)
+	}
+
+	if len(fc.Keys) > 0 {
+		keys := formatter.DefaultJSONKeys
+		fields := map[string]*string{
+			"module":     &keys.Module,
+			"level_name": &keys.LevelName,
+			"level":      &keys.Level,
+			"context":    &keys.Context,
+			"message":    &keys.Message,
+			"datetime":   &keys.DateTime,
+		}
+
+		for k, v := range fc.Keys {
+			field, ok := fields[k]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %s", k)
+			}
+			*field = v
+		}
+
+		f.Keys(keys)
+	}
+
+	return f, nil
+}
+
+var facilities = map[string]formatter.Facility{
+	"":       formatter.FacilityUser,
+	"user":   formatter.FacilityUser,
+	"daemon": formatter.FacilityDaemon,
+	"local0": formatter.FacilityLocal0,
+	"local1": formatter.FacilityLocal1,
+	"local2": formatter.FacilityLocal2,
+	"local3": formatter.FacilityLocal3,
+	"local4": formatter.FacilityLocal4,
+	"local5": formatter.FacilityLocal5,
+	"local6": formatter.FacilityLocal6,
+	"local7": formatter.FacilityLocal7,
+}
+
+func (b *builder) writer(name string) (writer.Writer, error) {
+	if w, ok := b.writers[name]; ok {
+		return w, nil
+	}
+
+	wc, ok := b.conf.Writers[name]
+	if !ok {
+		return nil, fmt.Errorf("writer %s not defined", name)
+	}
+
+	if b.building[name] {
+		return nil, fmt.Errorf("writer %s: circular reference", name)
+	}
+	b.building[name] = true
+	defer delete(b.building, name)
+
+	w, err := b.newWriter(wc)
+	if err != nil {
+		return nil, fmt.Errorf("writer %s: %s", name, err)
+	}
+
+	if wc.Async != nil {
+		w, err = newAsyncWriter(w, wc.Async)
+		if err != nil {
+			return nil, fmt.Errorf("writer %s: %s", name, err)
+		}
+	}
+
+	b.writers[name] = w
+	return w, nil
+}
+
+func (b *builder) newWriter(wc WriterConfig) (writer.Writer, error) {
+	switch wc.Type {
+	case "file":
+		if wc.Filename == "" {
+			return nil, fmt.Errorf("filename is required")
+		}
+		return writer.NewDefaultFileWriter(wc.Filename), nil
+	case "rotating":
+		return b.newRotatingWriter(wc)
+	case "stream":
+		switch wc.Target {
+		case "", "stdout":
+			return writer.NewStdoutWriter(), nil
+		case "stderr":
+			return writer.NewStreamWriter(os.Stderr), nil
+		}
+		return nil, fmt.Errorf("unsupported stream target %s", wc.Target)
+	case "syslog":
+		if wc.Address == "" {
+			return newLocalSyslogWriter(wc.Tag)
+		}
+		network := wc.Network
+		if network == "" {
+			network = "udp"
+		}
+		return writer.NewRFC5424Writer(network, wc.Address), nil
+	case "stack":
+		return b.newStackWriter(wc)
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", wc.Type)
+}
+
+func (b *builder) newStackWriter(wc WriterConfig) (writer.Writer, error) {
+	modes := map[string]writer.StackMode{
+		"":           writer.StackSequential,
+		"sequential": writer.StackSequential,
+		"all":        writer.StackAll,
+		"parallel":   writer.StackParallel,
+	}
+
+	mode, ok := modes[wc.Mode]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stack mode %s", wc.Mode)
+	}
+
+	timeout, err := parseDuration(wc.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	stack := writer.NewStackWriter().Mode(mode).Timeout(timeout)
+	for _, item := range wc.Writers {
+		w, err := b.writer(item.Writer)
+		if err != nil {
+			return nil, err
+		}
+
+		levels := make([]level.Level, 0, len(item.Levels))
+		for _, l := range item.Levels {
+			le, err := parseLevel(l)
+			if err != nil {
+				return nil, err
+			}
+			levels = append(levels, le)
+		}
+
+		stack.PushNamedWithLevels(item.Writer, w, levels...)
+	}
+
+	return stack, nil
+}
+
+func (b *builder) newRotatingWriter(wc WriterConfig) (writer.Writer, error) {
+	maxAge, err := parseDuration(wc.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case wc.Pattern != "" && wc.MaxSize > 0:
+		return nil, fmt.Errorf("pattern and max_size can not be used together")
+	case wc.Pattern != "":
+		w := writer.NewTimeRotatingFileWriter(b.ctx, wc.Pattern).MaxAge(maxAge)
+		if wc.Symlink != "" {
+			w.Symlink(wc.Symlink)
+		}
+		return w, nil
+	case wc.MaxSize > 0 && wc.Filename != "":
+		return writer.NewSizeRotatingFileWriter(wc.Filename, wc.MaxSize).
+			MaxBackups(wc.MaxBackups).
+			MaxAge(maxAge).
+			Compress(wc.Compress), nil
+	}
+
+	return nil, fmt.Errorf("pattern or filename with max_size is required")
+}
+
+func newAsyncWriter(w writer.Writer, ac *AsyncConfig) (writer.Writer, error) {
+	policies := map[string]writer.OverflowPolicy{
+		"":                 writer.OverflowBlock,
+		"block":            writer.OverflowBlock,
+		"drop_newest":      writer.OverflowDropNewest,
+		"drop_oldest":      writer.OverflowDropOldest,
+		"drop_below_level": writer.OverflowDropBelowLevel,
+	}
+
+	policy, ok := policies[ac.Overflow]
+	if !ok {
+		return nil, fmt.Errorf("unsupported overflow policy %s", ac.Overflow)
+	}
+
+	queueSize := ac.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+
+	aw := writer.NewAsyncWriter(w, queueSize, policy)
+	if ac.DropLevel != "" {
+		le, err := parseLevel(ac.DropLevel)
+		if err != nil {
+			_ = aw.Close()
+			return nil, err
+		}
+		aw.DropLevel(le)
+	}
+
+	return aw, nil
+}
@@ -0,0 +1,166 @@
+// Package config load logger configuration from YAML or JSON documents
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration for all loggers
+type Config struct {
+	// Default is the default config for all modules
+	Default ModuleConfig `yaml:"default" json:"default"`
+	// Modules is the config for individual modules, keyed by module name
+	Modules map[string]ModuleConfig `yaml:"modules" json:"modules"`
+	// Writers is the named writers which can be referenced by modules
+	Writers map[string]WriterConfig `yaml:"writers" json:"writers"`
+	// Formatters is the named formatters which can be referenced by modules
+	Formatters map[string]FormatterConfig `yaml:"formatters" json:"formatters"`
+}
+
+// ModuleConfig is the config for a module, empty fields will not be changed
+type ModuleConfig struct {
+	Level     string `yaml:"level" json:"level"`
+	Formatter string `yaml:"formatter" json:"formatter"`
+	Writer    string `yaml:"writer" json:"writer"`
+	FileLine  *bool  `yaml:"file_line" json:"file_line"`
+}
+
+// WriterConfig is the config for a writer
+type WriterConfig struct {
+	// Type is one of file, rotating, stack, syslog, stream
+	Type string `yaml:"type" json:"type"`
+
+	// Filename for file and rotating writer
+	Filename string `yaml:"filename" json:"filename"`
+	// MaxSize (in bytes) for size based rotating writer
+	MaxSize int64 `yaml:"max_size" json:"max_size"`
+	// MaxBackups for size based rotating writer
+	MaxBackups int `yaml:"max_backups" json:"max_backups"`
+	// Compress whether gzip the backups for size based rotating writer
+	Compress bool `yaml:"compress" json:"compress"`
+	// Pattern is the strftime-style pattern for time based rotating writer
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Symlink for time based rotating writer
+	Symlink string `yaml:"symlink" json:"symlink"`
+	// MaxAge is a duration such as 168h for rotating writers
+	MaxAge string `yaml:"max_age" json:"max_age"`
+
+	// Target is stdout or stderr for stream writer
+	Target string `yaml:"target" json:"target"`
+
+	// Network, Address for syslog writer, local syslog will be used if address is empty
+	Network string `yaml:"network" json:"network"`
+	Address string `yaml:"address" json:"address"`
+	// Tag for local syslog writer
+	Tag string `yaml:"tag" json:"tag"`
+
+	// Writers for stack writer
+	Writers []StackItemConfig `yaml:"writers" json:"writers"`
+	// Mode for stack writer, one of sequential, all, parallel
+	Mode string `yaml:"mode" json:"mode"`
+	// Timeout is a duration for each writer in parallel stack writer
+	Timeout string `yaml:"timeout" json:"timeout"`
+
+	// Async wrap the writer with an AsyncWriter
+	Async *AsyncConfig `yaml:"async" json:"async"`
+}
+
+// StackItemConfig is a writer in stack writer
+type StackItemConfig struct {
+	Writer string   `yaml:"writer" json:"writer"`
+	Levels []string `yaml:"levels" json:"levels"`
+}
+
+// AsyncConfig is the config for AsyncWriter
+type AsyncConfig struct {
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+	// Overflow is one of block, drop_newest, drop_oldest, drop_below_level
+	Overflow  string `yaml:"overflow" json:"overflow"`
+	DropLevel string `yaml:"drop_level" json:"drop_level"`
+}
+
+// FormatterConfig is the config for a formatter
+type FormatterConfig struct {
+	// Type is one of default, json, json_with_time, logfmt, template, gelf, rfc5424, ecs
+	Type string `yaml:"type" json:"type"`
+	// Colorful for default formatter
+	Colorful bool `yaml:"colorful" json:"colorful"`
+	// Host for gelf and rfc5424 formatter, os.Hostname() will be used if empty
+	Host string `yaml:"host" json:"host"`
+	// Facility for rfc5424 formatter, such as user, daemon, local0-local7
+	Facility string `yaml:"facility" json:"facility"`
+	// Template is the pattern for template formatter, see formatter.TemplateFormatter
+	Template string `yaml:"template" json:"template"`
+	// Keys rename the keys of json formatter, such as {"datetime": "@timestamp"}, empty value to omit the key
+	Keys map[string]string `yaml:"keys" json:"keys"`
+	// TimeFormat for json formatter, one of rfc3339, rfc3339nano, unix, unixmilli, or a layout
+	TimeFormat string `yaml:"time_format" json:"time_format"`
+	// Flatten write the fields to the top level for json formatter
+	Flatten bool `yaml:"flatten" json:"flatten"`
+	// GlobalPrefix is the prefix for global fields (such as file, line) for json formatter, default is #
+	GlobalPrefix *string `yaml:"global_prefix" json:"global_prefix"`
+	// Namespace is the key of object for custom fields for ecs formatter, default is asteria, empty for the top level
+	Namespace *string `yaml:"namespace" json:"namespace"`
+	// Labels for ecs formatter
+	Labels map[string]string `yaml:"labels" json:"labels"`
+}
+
+// Parse the config, format is yaml or json
+func Parse(data []byte, format string) (*Config, error) {
+	var conf Config
+
+	switch strings.ToLower(format) {
+	case "json":
+		if err := json.Unmarshal(data, &conf); err != nil {
+			return nil, err
+		}
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, &conf); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+
+	return &conf, nil
+}
+
+// Load the config from file, the format is decided by file extension, .json for json and others for yaml
+func Load(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	format := "yaml"
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		format = "json"
+	}
+
+	return Parse(data, format)
+}
+
+func parseLevel(name string) (level.Level, error) {
+	le := level.GetLevelByName(name)
+	if le == 0 {
+		return 0, fmt.Errorf("invalid level: %s", name)
+	}
+
+	return le, nil
+}
+
+func parseDuration(d string) (time.Duration, error) {
+	if d == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(d)
+}
@@ -0,0 +1,282 @@
+package config_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/config"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+default:
+  level: info
+  formatter: json
+  writer: all
+modules:
+  user:
+    level: debug
+    writer: user
+writers:
+  all:
+    type: stack
+    mode: all
+    writers:
+      - writer: error
+        levels: [error]
+      - writer: user
+  error:
+    type: file
+    filename: __DIR__/error.log
+  user:
+    type: file
+    filename: __DIR__/user.log
+    async:
+      queue_size: 10
+formatters:
+  json:
+    type: json
+`
+
+func readFile(t *testing.T, filename string) string {
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func writeConfig(t *testing.T, filename string, content string) {
+	dir := filepath.Dir(filename)
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(replaceDir(content, dir)), os.ModePerm))
+}
+
+func replaceDir(content string, dir string) string {
+	return strings.Replace(content, "__DIR__", dir, -1)
+}
+
+func TestParse(t *testing.T) {
+	conf, err := config.Parse([]byte(yamlConfig), "yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "info", conf.Default.Level)
+	assert.Equal(t, "user", conf.Modules["user"].Writer)
+	assert.Equal(t, "stack", conf.Writers["all"].Type)
+	assert.Equal(t, []string{"error"}, conf.Writers["all"].Writers[0].Levels)
+	assert.Equal(t, 10, conf.Writers["user"].Async.QueueSize)
+
+	conf, err = config.Parse([]byte(`{"default": {"level": "error"}, "writers": {"stdout": {"type": "stream"}}}`), "json")
+	assert.NoError(t, err)
+	assert.Equal(t, "error", conf.Default.Level)
+	assert.Equal(t, "stream", conf.Writers["stdout"].Type)
+
+	_, err = config.Parse([]byte(""), "toml")
+	assert.Error(t, err)
+}
+
+func TestConfig_Apply(t *testing.T) {
+	log.Reset()
+
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf, err := config.Parse([]byte(replaceDir(yamlConfig, dir)), "yaml")
+	assert.NoError(t, err)
+
+	applied, err := conf.Apply(context.TODO())
+	assert.NoError(t, err)
+
+	assert.Equal(t, level.Info, log.GetDefaultConfig().LogLevel)
+	assert.False(t, log.Module("order").DebugEnabled())
+	assert.True(t, log.Module("user").DebugEnabled())
+
+	log.Module("order").Error("order failed")
+	log.Module("order").Info("order created")
+	log.Module("user").Debug("user created")
+
+	applied.Close()
+
+	assert.Contains(t, readFile(t, filepath.Join(dir, "error.log")), `"message":"order failed"`)
+	assert.NotContains(t, readFile(t, filepath.Join(dir, "error.log")), "order created")
+
+	userLog := readFile(t, filepath.Join(dir, "user.log"))
+	assert.Contains(t, userLog, "order failed")
+	assert.Contains(t, userLog, "order created")
+	assert.Contains(t, userLog, "user created")
+}
+
+func TestConfig_ApplyJSONFormatter(t *testing.T) {
+	log.Reset()
+
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf, err := config.Parse([]byte(replaceDir(`
+default:
+  formatter: ecs
+  writer: file
+writers:
+  file:
+    type: file
+    filename: __DIR__/app.log
+formatters:
+  ecs:
+    type: json
+    time_format: unixmilli
+    flatten: true
+    global_prefix: ""
+    keys:
+      datetime: "@timestamp"
+      level_name: log.level
+      module: log.logger
+      level: ""
+`, dir)), "yaml")
+	assert.NoError(t, err)
+
+	applied, err := conf.Apply(context.TODO())
+	assert.NoError(t, err)
+
+	log.Module("user").WithFields(log.Fields{"user_id": 123}).Error("user created")
+	applied.Close()
+
+	content := readFile(t, filepath.Join(dir, "app.log"))
+	assert.Contains(t, content, `"log.logger":"user","log.level":"ERROR","user_id":123`)
+	assert.Contains(t, content, `"file":"`)
+	assert.Regexp(t, `"@timestamp":\d{13}}`, content)
+	assert.NotContains(t, content, `"level":`)
+}
+
+func TestConfig_ApplyRules(t *testing.T) {
+	log.Reset()
+
+	rule := log.Rule("billing.*").LogLevel(level.Debug)
+	defer rule.Remove()
+
+	conf, err := config.Parse([]byte(`{"default": {"level": "error"}, "modules": {"billing.payment": {"level": "warning"}}}`), "json")
+	assert.NoError(t, err)
+
+	// the rules added in code are kept after applying the config
+	for i := 0; i < 2; i++ {
+		applied, err := conf.Apply(context.Background())
+		assert.NoError(t, err)
+
+		assert.True(t, log.Module("billing.invoice").DebugEnabled())
+		assert.False(t, log.Module("billing.payment").InfoEnabled())
+		assert.False(t, log.Module("order").WarningEnabled())
+
+		applied.Close()
+	}
+}
+
+func TestConfig_ApplyClose(t *testing.T) {
+	log.Reset()
+
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf, err := config.Parse([]byte(replaceDir(`
+default:
+  writer: rotating
+writers:
+  rotating:
+    type: rotating
+    pattern: __DIR__/app-%Y%m%d.log
+    max_age: 24h
+`, dir)), "yaml")
+	assert.NoError(t, err)
+
+	before := runtime.NumGoroutine()
+
+	// the background goroutines of writers stop when the applied config is closed
+	for i := 0; i < 10; i++ {
+		applied, err := conf.Apply(context.Background())
+		assert.NoError(t, err)
+		applied.Close()
+	}
+
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= before)
+}
+
+func TestConfig_ApplyInvalid(t *testing.T) {
+	log.Reset()
+	log.Module("user").LogLevel(level.Error)
+
+	var testCases = []string{
+		`{"default": {"level": "unknown"}}`,
+		`{"default": {"writer": "missing"}}`,
+		`{"default": {"level": "debug"}, "modules": {"user": {"formatter": "missing"}}}`,
+		`{"writers": {"a": {"type": "stack", "writers": [{"writer": "b"}]}, "b": {"type": "stack", "writers": [{"writer": "a"}]}}}`,
+		`{"writers": {"rotating": {"type": "rotating"}}}`,
+		`{"writers": {"stack": {"type": "stack", "mode": "unknown"}}}`,
+		`{"writers": {"unknown": {"type": "unknown"}}}`,
+		`{"formatters": {"unknown": {"type": "unknown"}}}`,
+		`{"formatters": {"template": {"type": "template", "template": "{unknown}"}}}`,
+		`{"formatters": {"json": {"type": "json", "keys": {"unknown": "x"}}}}`,
+	}
+
+	for _, tc := range testCases {
+		conf, err := config.Parse([]byte(tc), "json")
+		assert.NoError(t, err)
+
+		_, err = conf.Apply(context.TODO())
+		assert.Error(t, err, tc)
+
+		assert.Equal(t, level.Debug, log.GetDefaultConfig().LogLevel)
+		assert.False(t, log.Module("user").DebugEnabled())
+	}
+}
+
+func TestLoader_Watch(t *testing.T) {
+	log.Reset()
+	defer log.Reset()
+
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	configFile := filepath.Join(dir, "log.yaml")
+	writeConfig(t, configFile, yamlConfig)
+
+	loader := config.NewLoader(ctx, configFile)
+	assert.NoError(t, loader.Load())
+
+	log.Module("user").Debug("before reload")
+
+	errs := make(chan error, 1)
+	loader.Watch(10*time.Millisecond, func(err error) { errs <- err })
+
+	// invalid config will be reported, and current config is kept
+	writeConfig(t, configFile, "default:\n  level: unknown\n")
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Error("invalid config not reported")
+	}
+	assert.True(t, log.Module("user").DebugEnabled())
+
+	writeConfig(t, configFile, "default:\n  level: error\n")
+	for i := 0; i < 100 && log.Module("user").DebugEnabled(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.False(t, log.Module("user").DebugEnabled())
+	assert.Equal(t, level.Error, log.GetDefaultConfig().LogLevel)
+
+	// writers of last config are closed, buffered messages are flushed
+	assert.Contains(t, readFile(t, filepath.Join(dir, "user.log")), "before reload")
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// Loader load config from file and apply it to loggers, it can watch the file and reapply the changes
+type Loader struct {
+	ctx      context.Context
+	filename string
+	base     log.DefaultConfig
+
+	applied *Applied
+	modTime time.Time
+	size    int64
+
+	lock sync.Mutex
+}
+
+// NewLoader create a new Loader
+//
+// The current DefaultConfig is kept as base, settings removed from default section
+// of the config file will be restored to it when reloading
+func NewLoader(ctx context.Context, filename string) *Loader {
+	return &Loader{
+		ctx:      ctx,
+		filename: filename,
+		base:     log.GetDefaultConfig(),
+	}
+}
+
+// Load the config file and apply it, writers created by the last load will be closed after the new config applied,
+// so that buffered messages will be flushed
+//
+// If the config is invalid, the current config will be kept
+func (loader *Loader) Load() error {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+
+	stat, err := os.Stat(loader.filename)
+	if err != nil {
+		return err
+	}
+
+	// the file will not be reloaded until it is changed again, even if it is invalid
+	loader.modTime = stat.ModTime()
+	loader.size = stat.Size()
+
+	conf, err := Load(loader.filename)
+	if err != nil {
+		return err
+	}
+
+	applied, err := conf.apply(loader.ctx, loader.base)
+	if err != nil {
+		return err
+	}
+
+	if loader.applied != nil {
+		loader.applied.Close()
+	}
+
+	loader.applied = applied
+
+	return nil
+}
+
+// Watch check the config file every interval, reload it if changed
+//
+// It stops when the ctx of Loader is done, errors occurred when reloading are passed to onError
+func (loader *Loader) Watch(interval time.Duration, onError func(err error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if !loader.changed() {
+					continue
+				}
+
+				if err := loader.Load(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-loader.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Applied return the writers created by the last load
+func (loader *Loader) Applied() *Applied {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+
+	return loader.applied
+}
+
+func (loader *Loader) changed() bool {
+	stat, err := os.Stat(loader.filename)
+	if err != nil {
+		return false
+	}
+
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+
+	return !stat.ModTime().Equal(loader.modTime) || stat.Size() != loader.size
+}
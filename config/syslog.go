@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"log/syslog"
+
+	"github.com/mylxsw/asteria/writer"
+)
+
+func newLocalSyslogWriter(tag string) (writer.Writer, error) {
+	return writer.NewSyslogWriter("", "", syslog.LOG_USER, tag), nil
+}
@@ -0,0 +1,11 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/mylxsw/asteria/writer"
+)
+
+func newLocalSyslogWriter(tag string) (writer.Writer, error) {
+	return nil, fmt.Errorf("local syslog is not supported on windows, address is required")
+}
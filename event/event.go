@@ -15,11 +15,12 @@ type Fields struct {
 }
 
 type Event struct {
-	Time     time.Time
-	Module   string
-	Level    level.Level
-	Fields   Fields
-	Messages []interface{}
+	Time        time.Time
+	Module      string
+	Level       level.Level
+	Fields      Fields
+	Messages    []interface{}
+	TypedFields []Field
 }
 
 func (f Fields) String(excludes ...string) string {
@@ -27,10 +28,12 @@ func (f Fields) String(excludes ...string) string {
 	return string(encoded)
 }
 
+// ToMap return a new map with CustomFields and GlobalFields prefixed with #, the fields are not modified,
+// so that the event can be formatted by several formatters
 func (f Fields) ToMap(excludes ...string) map[string]interface{} {
-	cc := f.CustomFields
-	if cc == nil {
-		cc = make(map[string]interface{}, 0)
+	cc := make(map[string]interface{}, len(f.CustomFields)+len(f.GlobalFields))
+	for k, v := range f.CustomFields {
+		cc[k] = v
 	}
 
 	for k, v := range f.GlobalFields {
@@ -34,6 +34,10 @@ func TestFields_ToMap(t *testing.T) {
 
 	assert.Equal(t, "abcdef", res["#ref"])
 	assert.Equal(t, 123, res["user_id"])
+
+	// the fields are not modified
+	assert.Len(t, ev.Fields.CustomFields, 1)
+	assert.NotContains(t, ev.Fields.CustomFields, "#ref")
 }
 
 func TestFields_String(t *testing.T) {
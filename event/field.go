@@ -0,0 +1,230 @@
+package event
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldType is the type of value a Field holds
+type FieldType uint8
+
+const (
+	UnknownType FieldType = iota
+	StringType
+	IntType
+	FloatType
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	AnyType
+)
+
+// Field is a typed field, the value is stored without boxing into interface{},
+// so it can be written to the output without building a map
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Float     float64
+	Interface interface{}
+}
+
+// Value return the value of field
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return f.Integer
+	case FloatType:
+		return f.Float
+	case BoolType:
+		return f.Integer == 1
+	case DurationType:
+		return time.Duration(f.Integer)
+	case TimeType:
+		return f.time()
+	case ErrorType:
+		if f.Interface == nil {
+			return nil
+		}
+		return f.Interface.(error).Error()
+	default:
+		return f.Interface
+	}
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.Integer)
+	if loc, ok := f.Interface.(*time.Location); ok && loc != nil {
+		return t.In(loc)
+	}
+
+	return t
+}
+
+// AppendJSON append the field as `"key":value` to dst
+func (f Field) AppendJSON(dst []byte) []byte {
+	dst = appendJSONString(dst, f.Key)
+	dst = append(dst, ':')
+	return f.AppendJSONValue(dst)
+}
+
+// AppendJSONValue append the value of field as json to dst
+func (f Field) AppendJSONValue(dst []byte) []byte {
+	switch f.Type {
+	case StringType:
+		return appendJSONString(dst, f.String)
+	case IntType, DurationType:
+		return strconv.AppendInt(dst, f.Integer, 10)
+	case FloatType:
+		if math.IsNaN(f.Float) || math.IsInf(f.Float, 0) {
+			return appendJSONString(dst, strconv.FormatFloat(f.Float, 'f', -1, 64))
+		}
+		return strconv.AppendFloat(dst, f.Float, 'f', -1, 64)
+	case BoolType:
+		return strconv.AppendBool(dst, f.Integer == 1)
+	case TimeType:
+		dst = append(dst, '"')
+		dst = f.time().AppendFormat(dst, time.RFC3339Nano)
+		return append(dst, '"')
+	case ErrorType:
+		if f.Interface == nil {
+			return append(dst, "null"...)
+		}
+		return appendJSONString(dst, f.Interface.(error).Error())
+	default:
+		encoded, err := json.Marshal(f.Interface)
+		if err != nil {
+			return appendJSONString(dst, fmt.Sprintf("!ERROR: %v", err))
+		}
+		return append(dst, encoded...)
+	}
+}
+
+// AppendFieldsJSON append all fields (including typed fields) of event to dst as a json object,
+// GlobalFields are prefixed with # like Fields.ToMap, each key is written once as RangeFields
+func (e Event) AppendFieldsJSON(dst []byte, excludes ...string) []byte {
+	stream := json.BorrowStream(nil)
+	buf := stream.Buffer()
+	defer func() {
+		// the pooled stream must not hold dst
+		stream.SetBuffer(buf[:0])
+		json.ReturnStream(stream)
+	}()
+
+	stream.SetBuffer(append(dst, '{'))
+	first := true
+	e.RangeFields("#", excludes, func(key string, value interface{}, typed int) {
+		if !first {
+			stream.WriteMore()
+		}
+		first = false
+
+		stream.SetBuffer(append(appendJSONString(stream.Buffer(), key), ':'))
+		if typed >= 0 {
+			stream.SetBuffer(e.TypedFields[typed].AppendJSONValue(stream.Buffer()))
+		} else {
+			stream.WriteVal(value)
+		}
+	})
+
+	return append(stream.Buffer(), '}')
+}
+
+// RangeFields call fn once for each key of CustomFields, GlobalFields (prefixed with globalPrefix,
+// those in excludes are skipped) and TypedFields, in this order. Typed fields take precedence over
+// CustomFields, then GlobalFields, and the last typed field wins if a key is added several times.
+// typed is the index of TypedFields for typed fields (value is nil), -1 for the others
+func (e Event) RangeFields(globalPrefix string, excludes []string, fn func(key string, value interface{}, typed int)) {
+	for k, v := range e.Fields.CustomFields {
+		if e.TypedFieldIndex(k) < 0 {
+			fn(k, v, -1)
+		}
+	}
+
+	for k, v := range e.Fields.GlobalFields {
+		if strIn(k, excludes) {
+			continue
+		}
+
+		key := globalPrefix + k
+		if _, ok := e.Fields.CustomFields[key]; ok || e.TypedFieldIndex(key) >= 0 {
+			continue
+		}
+
+		fn(key, v, -1)
+	}
+
+	for i, tf := range e.TypedFields {
+		if e.TypedFieldIndex(tf.Key) == i {
+			fn(tf.Key, nil, i)
+		}
+	}
+}
+
+// TypedFieldIndex return the index of the last typed field with the key, -1 if not found
+func (e Event) TypedFieldIndex(key string) int {
+	for i := len(e.TypedFields) - 1; i >= 0; i-- {
+		if e.TypedFields[i].Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString append s to dst as a quoted json string
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,81 @@
+package event_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField_AppendJSON(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	cases := []struct {
+		field    event.Field
+		expected string
+	}{
+		{event.Field{Key: "name", Type: event.StringType, String: "Tom \"Cat\"\n\t\x01"}, `"name":"Tom \"Cat\"\n\t\u0001"`},
+		{event.Field{Key: "id", Type: event.IntType, Integer: -123}, `"id":-123`},
+		{event.Field{Key: "rate", Type: event.FloatType, Float: 33.4}, `"rate":33.4`},
+		{event.Field{Key: "ok", Type: event.BoolType, Integer: 1}, `"ok":true`},
+		{event.Field{Key: "elapsed", Type: event.DurationType, Integer: int64(time.Second)}, `"elapsed":1000000000`},
+		{event.Field{Key: "at", Type: event.TimeType, Integer: time.Date(2019, 7, 17, 17, 5, 4, 0, loc).UnixNano(), Interface: loc}, `"at":"2019-07-17T17:05:04+08:00"`},
+		{event.Field{Key: "error", Type: event.ErrorType, Interface: errors.New("not found")}, `"error":"not found"`},
+		{event.Field{Key: "error", Type: event.ErrorType}, `"error":null`},
+		{event.Field{Key: "user", Type: event.AnyType, Interface: map[string]int{"id": 1}}, `"user":{"id":1}`},
+		{event.Field{Key: "中文", Type: event.StringType, String: "你好"}, `"中文":"你好"`},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, string(c.field.AppendJSON(nil)))
+	}
+}
+
+func TestField_Value(t *testing.T) {
+	assert.Equal(t, "Tom", event.Field{Type: event.StringType, String: "Tom"}.Value())
+	assert.Equal(t, int64(12), event.Field{Type: event.IntType, Integer: 12}.Value())
+	assert.Equal(t, false, event.Field{Type: event.BoolType}.Value())
+	assert.Equal(t, time.Second, event.Field{Type: event.DurationType, Integer: int64(time.Second)}.Value())
+	assert.Equal(t, "not found", event.Field{Type: event.ErrorType, Interface: errors.New("not found")}.Value())
+}
+
+func TestEvent_AppendFieldsJSON(t *testing.T) {
+	evt := event.Event{
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{"user_id": 123},
+			GlobalFields: map[string]interface{}{"ref": "abcdef", "stacktrace": "..."},
+		},
+		TypedFields: []event.Field{
+			{Key: "name", Type: event.StringType, String: "Tom"},
+			{Key: "age", Type: event.IntType, Integer: 18},
+		},
+	}
+
+	assert.JSONEq(t, `{"user_id":123,"#ref":"abcdef","name":"Tom","age":18}`, string(evt.AppendFieldsJSON(nil, "stacktrace")))
+
+	evt.Fields = event.Fields{}
+	assert.Equal(t, `{"name":"Tom","age":18}`, string(evt.AppendFieldsJSON(nil)))
+
+	evt.TypedFields = nil
+	assert.Equal(t, `{}`, string(evt.AppendFieldsJSON(nil)))
+}
+
+func TestEvent_AppendFieldsJSON_DuplicateKeys(t *testing.T) {
+	evt := event.Event{
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{"uid": 1, "name": "Tom", "#ref": "custom"},
+			GlobalFields: map[string]interface{}{"ref": "global", "host": "localhost"},
+		},
+		TypedFields: []event.Field{
+			{Key: "uid", Type: event.IntType, Integer: 2},
+			{Key: "uid", Type: event.IntType, Integer: 3},
+		},
+	}
+
+	encoded := string(evt.AppendFieldsJSON(nil))
+	assert.Equal(t, 1, strings.Count(encoded, `"uid"`))
+	assert.Equal(t, 1, strings.Count(encoded, `"#ref"`))
+	assert.JSONEq(t, `{"uid":3,"name":"Tom","#ref":"custom","#host":"localhost"}`, encoded)
+}
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,39 +32,73 @@ func NewDefaultCleanFormatter(colorful bool) *DefaultFormatter {
 
 // Format 日志格式化
 func (formatter DefaultFormatter) Format(f event.Event) string {
-	var message, messageBody string
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the formatted event to dst
+func (formatter DefaultFormatter) AppendFormat(dst []byte, f event.Event) []byte {
+	var messageBody string
 	if formatter.stripCtrlAndExtFromUnicode {
 		messageBody = StripCtlAndExtFromUnicode(strings.Trim(fmt.Sprint(f.Messages...), "\n"))
 	} else {
 		messageBody = strings.Trim(fmt.Sprint(f.Messages...), "\n")
 	}
 
+	start := len(dst)
+
+	dst = append(dst, '[')
+	dst = f.Time.AppendFormat(dst, time.RFC3339)
+	dst = append(dst, "] "...)
+
 	if formatter.colorful {
-		message = fmt.Sprintf(
-			"[%s] %s %s %s %s",
-			f.Time.Format(time.RFC3339),
-			misc.ColorfulLevelName(f.Level),
-			misc.ModuleNameAbbr(f.Module),
-			messageBody,
-			color.TextWrap(color.LightGrey, f.Fields.String("stacktrace")),
-		)
+		dst = append(dst, misc.ColorfulLevelName(f.Level)...)
+		dst = append(dst, ' ')
+		dst = append(dst, misc.ModuleNameAbbr(f.Module)...)
 	} else {
-		message = fmt.Sprintf(
-			"[%s] %s %s %s %s",
-			f.Time.Format(time.RFC3339),
-			f.Level.GetLevelName(),
-			f.Module,
-			messageBody,
-			f.Fields.String("stacktrace"),
-		)
+		dst = append(dst, f.Level.GetLevelName()...)
+		dst = append(dst, ' ')
+		dst = append(dst, f.Module...)
+	}
+
+	dst = append(dst, ' ')
+	dst = appendIndented(dst, messageBody)
+	dst = append(dst, ' ')
+
+	if formatter.colorful {
+		dst = append(dst, "\x1b["...)
+		dst = strconv.AppendInt(dst, int64(color.LightGrey), 10)
+		dst = append(dst, 'm')
+		dst = f.AppendFieldsJSON(dst, "stacktrace")
+		dst = append(dst, "\x1b[0m"...)
+	} else {
+		dst = f.AppendFieldsJSON(dst, "stacktrace")
 	}
 
 	if stacktrace, ok := f.Fields.GlobalFields["stacktrace"]; ok {
-		message = fmt.Sprintf("%s\n%s", message, stacktrace)
+		dst = append(dst, "\n\t"...)
+		dst = appendIndented(dst, fmt.Sprint(stacktrace))
+	}
+
+	// 多行内容已增加前缀tab，与第一行内容分开，去掉末尾的换行与tab
+	for len(dst) > start && (dst[len(dst)-1] == '\n' || dst[len(dst)-1] == '\t') {
+		dst = dst[:len(dst)-1]
 	}
 
-	// 将多行内容增加前缀tab，与第一行内容分开
-	return strings.Trim(strings.Replace(message, "\n", "\n	", -1), "\n	")
+	return dst
+}
+
+// appendIndented append s to dst, and add a tab after each new line
+func appendIndented(dst []byte, s string) []byte {
+	for {
+		pos := strings.IndexByte(s, '\n')
+		if pos < 0 {
+			return append(dst, s...)
+		}
+
+		dst = append(dst, s[:pos+1]...)
+		dst = append(dst, '\t')
+		s = s[pos+1:]
+	}
 }
 
 // StripCtlAndExtFromUnicode Advanced Unicode normalization and filtering,
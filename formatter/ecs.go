@@ -0,0 +1,221 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mylxsw/asteria/event"
+)
+
+// ECSVersion is the version of Elastic Common Schema written to ecs.version
+const ECSVersion = "1.6.0"
+
+const ecsTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// ecsReservedKeys are the keys written by ECSFormatter, fields with these keys are dropped when namespace is empty
+var ecsReservedKeys = []string{"@timestamp", "log.level", "message", "ecs.version", "log.logger", "log.origin", "error", "labels"}
+
+// ECSFormatter format the log as Elastic Common Schema (ECS) json, such as
+//
+//	{"@timestamp":"2019-07-17T09:05:04.000Z","log.level":"error","message":"user created","ecs.version":"1.6.0","log.logger":"asteria.user","log.origin":{"file":{"name":"user.go","line":12}},"asteria":{"user_id":123}}
+//
+// GlobalFields file/line are written to log.origin, stacktrace (from filter.WithStacktrace) to error.stack_trace,
+// the field error (such as log.Err) to error.type and error.message, the other fields are written under namespace
+type ECSFormatter struct {
+	namespace string
+	labels    map[string]string
+}
+
+// NewECSFormatter create a new ECSFormatter
+func NewECSFormatter() *ECSFormatter {
+	return &ECSFormatter{namespace: "asteria"}
+}
+
+// Namespace set the key of object for custom fields, default is asteria, empty to write them to the top level
+func (formatter *ECSFormatter) Namespace(namespace string) *ECSFormatter {
+	formatter.namespace = namespace
+	return formatter
+}
+
+// Labels set the labels written with every log, such as {"env": "production"}
+func (formatter *ECSFormatter) Labels(labels map[string]string) *ECSFormatter {
+	formatter.labels = labels
+	return formatter
+}
+
+// Format 日志格式化
+func (formatter ECSFormatter) Format(f event.Event) string {
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the ECS json to dst
+func (formatter ECSFormatter) AppendFormat(dst []byte, f event.Event) []byte {
+	return appendJSON(dst, func(stream *jsoniter.Stream) {
+		formatter.write(stream, f)
+	})
+}
+
+type ecsError struct {
+	typ        string
+	message    string
+	stackTrace string
+}
+
+func (formatter ECSFormatter) write(stream *jsoniter.Stream, f event.Event) {
+	stream.WriteObjectStart()
+	stream.WriteObjectField("@timestamp")
+	stream.WriteString(f.Time.UTC().Format(ecsTimeFormat))
+	stream.WriteMore()
+	stream.WriteObjectField("log.level")
+	stream.WriteString(strings.ToLower(f.Level.GetLevelName()))
+	stream.WriteMore()
+	stream.WriteObjectField("message")
+	stream.WriteString(fmt.Sprint(f.Messages...))
+	stream.WriteMore()
+	stream.WriteObjectField("ecs.version")
+	stream.WriteString(ECSVersion)
+	stream.WriteMore()
+	stream.WriteObjectField("log.logger")
+	stream.WriteString(f.Module)
+
+	file, hasFile := f.Fields.GlobalFields["file"]
+	line, hasLine := f.Fields.GlobalFields["line"]
+	if hasFile || hasLine {
+		stream.WriteMore()
+		stream.WriteObjectField("log.origin")
+		stream.WriteObjectStart()
+		stream.WriteObjectField("file")
+		stream.WriteObjectStart()
+		if hasFile {
+			stream.WriteObjectField("name")
+			stream.WriteVal(file)
+		}
+		if hasLine {
+			if hasFile {
+				stream.WriteMore()
+			}
+			stream.WriteObjectField("line")
+			stream.WriteVal(line)
+		}
+		stream.WriteObjectEnd()
+		stream.WriteObjectEnd()
+	}
+
+	errField, customErr, typedErr := ecsErrorField(f)
+	if errField.typ != "" || errField.message != "" || errField.stackTrace != "" {
+		stream.WriteMore()
+		stream.WriteObjectField("error")
+		stream.WriteObjectStart()
+		more := false
+		for _, kv := range [][2]string{{"type", errField.typ}, {"message", errField.message}, {"stack_trace", errField.stackTrace}} {
+			if kv[1] == "" {
+				continue
+			}
+			if more {
+				stream.WriteMore()
+			}
+			more = true
+			stream.WriteObjectField(kv[0])
+			stream.WriteString(kv[1])
+		}
+		stream.WriteObjectEnd()
+	}
+
+	if len(formatter.labels) > 0 {
+		keys := make([]string, 0, len(formatter.labels))
+		for k := range formatter.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		stream.WriteMore()
+		stream.WriteObjectField("labels")
+		stream.WriteObjectStart()
+		for i, k := range keys {
+			if i > 0 {
+				stream.WriteMore()
+			}
+			stream.WriteObjectField(k)
+			stream.WriteString(formatter.labels[k])
+		}
+		stream.WriteObjectEnd()
+	}
+
+	count := 0
+	field := func(key string) bool {
+		if formatter.namespace == "" {
+			if strIn(key, ecsReservedKeys) {
+				return false
+			}
+			stream.WriteMore()
+		} else if count == 0 {
+			stream.WriteMore()
+			stream.WriteObjectField(formatter.namespace)
+			stream.WriteObjectStart()
+		} else {
+			stream.WriteMore()
+		}
+
+		count++
+		stream.WriteObjectField(key)
+		return true
+	}
+
+	// each key is written once, with the same precedence as JSONFormatter
+	f.RangeFields("", []string{"file", "line", "stacktrace"}, func(key string, value interface{}, typed int) {
+		if typed < 0 && key == "error" && customErr || typed >= 0 && typed == typedErr {
+			return
+		}
+
+		if !field(key) {
+			return
+		}
+
+		if typed >= 0 {
+			stream.SetBuffer(f.TypedFields[typed].AppendJSONValue(stream.Buffer()))
+		} else {
+			stream.WriteVal(value)
+		}
+	})
+
+	if formatter.namespace != "" && count > 0 {
+		stream.WriteObjectEnd()
+	}
+
+	stream.WriteObjectEnd()
+}
+
+// ecsErrorField return the error of event, and whether the custom field error and which typed field (-1 for none)
+// are written as the error, typed fields take precedence over CustomFields
+func ecsErrorField(f event.Event) (res ecsError, customErr bool, typedErr int) {
+	typedErr = -1
+
+	if stacktrace, ok := f.Fields.GlobalFields["stacktrace"]; ok {
+		res.stackTrace = fmt.Sprint(stacktrace)
+	}
+
+	switch v := f.Fields.CustomFields["error"].(type) {
+	case error:
+		res.typ, res.message, customErr = fmt.Sprintf("%T", v), v.Error(), true
+	case string:
+		res.message, customErr = v, true
+	}
+
+	for i := len(f.TypedFields) - 1; i >= 0; i-- {
+		tf := f.TypedFields[i]
+		if tf.Key != "error" || tf.Type != event.ErrorType {
+			continue
+		}
+
+		if err, ok := tf.Interface.(error); ok {
+			res.typ, res.message = fmt.Sprintf("%T", err), err.Error()
+		}
+
+		typedErr = i
+		break
+	}
+
+	return
+}
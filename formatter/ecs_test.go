@@ -0,0 +1,114 @@
+package formatter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECSFormatter_Format(t *testing.T) {
+	f := formatter.NewECSFormatter().Labels(map[string]string{"env": "test", "app": "asteria"})
+
+	tm := time.Date(2019, 7, 17, 16, 58, 24, 123456000, time.FixedZone("CST", 8*3600))
+	res := f.Format(event.Event{
+		Time:   tm,
+		Module: "asteria.user",
+		Level:  level.Error,
+		Fields: event.Fields{
+			GlobalFields: map[string]interface{}{"file": "user.go", "line": 12, "package": "main", "stacktrace": "goroutine 1"},
+			CustomFields: map[string]interface{}{"user_id": 123, "error": errors.New("user exists")},
+		},
+		Messages: []interface{}{"user create failed"},
+	})
+
+	assert.JSONEq(t, `{
+		"@timestamp": "2019-07-17T08:58:24.123Z",
+		"log.level": "error",
+		"message": "user create failed",
+		"ecs.version": "`+formatter.ECSVersion+`",
+		"log.logger": "asteria.user",
+		"log.origin": {"file": {"name": "user.go", "line": 12}},
+		"error": {"type": "*errors.errorString", "message": "user exists", "stack_trace": "goroutine 1"},
+		"labels": {"app": "asteria", "env": "test"},
+		"asteria": {"package": "main", "user_id": 123}
+	}`, res)
+
+	res = formatter.NewECSFormatter().Namespace("").Format(event.Event{
+		Time:   tm,
+		Module: "asteria",
+		Level:  level.Info,
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{"message": "dropped", "error": "invalid password"},
+		},
+		TypedFields: []event.Field{
+			{Key: "user_id", Type: event.IntType, Integer: 123},
+		},
+		Messages: []interface{}{"user login failed"},
+	})
+
+	assert.JSONEq(t, `{
+		"@timestamp": "2019-07-17T08:58:24.123Z",
+		"log.level": "info",
+		"message": "user login failed",
+		"ecs.version": "`+formatter.ECSVersion+`",
+		"log.logger": "asteria",
+		"error": {"message": "invalid password"},
+		"user_id": 123
+	}`, res)
+
+	res = f.Namespace("app").Format(event.Event{
+		Time:   tm,
+		Module: "asteria",
+		Level:  level.Warning,
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{},
+			GlobalFields: map[string]interface{}{},
+		},
+		TypedFields: []event.Field{
+			{Key: "error", Type: event.ErrorType, Interface: errors.New("timeout")},
+			{Key: "retry", Type: event.BoolType, Integer: 1},
+		},
+		Messages: []interface{}{"request failed"},
+	})
+
+	assert.JSONEq(t, `{
+		"@timestamp": "2019-07-17T08:58:24.123Z",
+		"log.level": "warning",
+		"message": "request failed",
+		"ecs.version": "`+formatter.ECSVersion+`",
+		"log.logger": "asteria",
+		"error": {"type": "*errors.errorString", "message": "timeout"},
+		"labels": {"app": "asteria", "env": "test"},
+		"app": {"retry": true}
+	}`, res)
+}
+
+func TestECSFormatter_DuplicateKeys(t *testing.T) {
+	res := formatter.NewECSFormatter().Format(event.Event{
+		Time:   time.Now(),
+		Module: "asteria.user",
+		Level:  level.Info,
+		Fields: event.Fields{
+			GlobalFields: map[string]interface{}{"uid": 0, "host": "localhost"},
+			CustomFields: map[string]interface{}{"uid": 1, "name": "Tom"},
+		},
+		TypedFields: []event.Field{
+			{Key: "uid", Type: event.IntType, Integer: 2},
+			{Key: "uid", Type: event.IntType, Integer: 3},
+		},
+		Messages: []interface{}{"user created"},
+	})
+
+	assert.Equal(t, 1, strings.Count(res, `"uid"`))
+
+	var rs map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(res), &rs))
+	assert.Equal(t, map[string]interface{}{"uid": float64(3), "name": "Tom", "host": "localhost"}, rs["asteria"])
+}
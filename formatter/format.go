@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/misc"
 )
 
 // Formatter 日志格式化接口
@@ -9,3 +10,29 @@ type Formatter interface {
 	// Format 日志格式化
 	Format(f event.Event) string
 }
+
+// AppendFormatter is a Formatter which append the formatted event to dst, so that the
+// output can be built in a pooled buffer without allocating a string for every event
+type AppendFormatter interface {
+	Formatter
+	// AppendFormat append the formatted event to dst and return the extended buffer
+	AppendFormat(dst []byte, f event.Event) []byte
+}
+
+// AppendFormat append the event formatted by f to dst, Format is used if f is not an AppendFormatter
+func AppendFormat(f Formatter, dst []byte, e event.Event) []byte {
+	if af, ok := f.(AppendFormatter); ok {
+		return af.AppendFormat(dst, e)
+	}
+
+	return append(dst, f.Format(e)...)
+}
+
+// formatString is the Format for AppendFormatter, it formats the event in a pooled buffer
+func formatString(f AppendFormatter, e event.Event) string {
+	buf := misc.GetBuffer()
+	defer misc.PutBuffer(buf)
+
+	*buf = f.AppendFormat(*buf, e)
+	return string(*buf)
+}
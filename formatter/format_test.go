@@ -0,0 +1,53 @@
+package formatter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/stretchr/testify/assert"
+)
+
+type moduleFormatter struct{}
+
+func (moduleFormatter) Format(f event.Event) string {
+	return f.Module
+}
+
+func TestAppendFormat(t *testing.T) {
+	newEvent := func() event.Event {
+		return event.Event{
+			Time:   time.Date(2019, 7, 17, 17, 5, 4, 0, time.UTC),
+			Module: "asteria.user",
+			Level:  level.Error,
+			Fields: event.Fields{
+				CustomFields: map[string]interface{}{},
+				GlobalFields: map[string]interface{}{"stacktrace": "main.go:12\nmain.go:20"},
+			},
+			TypedFields: []event.Field{{Key: "user_id", Type: event.IntType, Integer: 123}},
+			Messages:    []interface{}{"user created\nsecond line"},
+		}
+	}
+
+	formatters := []formatter.AppendFormatter{
+		formatter.NewDefaultFormatter(false),
+		formatter.NewDefaultFormatter(true),
+		formatter.NewJSONFormatter(),
+		formatter.NewJSONWithTimeFormatter(),
+		formatter.NewRFC5424Formatter(formatter.FacilityLocal0).Hostname("web-01"),
+	}
+
+	for _, f := range formatters {
+		dst := []byte("prefix ")
+		assert.Equal(t, "prefix "+f.Format(newEvent()), string(f.AppendFormat(dst, newEvent())))
+		assert.Equal(t, f.Format(newEvent()), string(formatter.AppendFormat(f, nil, newEvent())))
+	}
+
+	// the keys of GELF message are not ordered
+	gelf := formatter.NewGELFFormatter().Host("web-01")
+	assert.JSONEq(t, gelf.Format(newEvent()), string(gelf.AppendFormat(nil, newEvent())))
+
+	assert.Equal(t, "prefix asteria.user", string(formatter.AppendFormat(moduleFormatter{}, []byte("prefix "), newEvent())))
+}
@@ -0,0 +1,98 @@
+package formatter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mylxsw/asteria/event"
+)
+
+var gelfInvalidKeyChars = regexp.MustCompile(`[^\w.\-]`)
+
+// GELFFormatter format the log as GELF 1.1 message for Graylog
+//
+// GlobalFields file/line/package are written as _file/_line/_package, and custom fields are prefixed with _
+type GELFFormatter struct {
+	host string
+}
+
+// NewGELFFormatter create a new GELFFormatter
+func NewGELFFormatter() *GELFFormatter {
+	host, _ := os.Hostname()
+	return &GELFFormatter{host: host}
+}
+
+// Host set the host field, default is os.Hostname()
+func (formatter *GELFFormatter) Host(host string) *GELFFormatter {
+	formatter.host = host
+	return formatter
+}
+
+// Format 日志格式化
+func (formatter GELFFormatter) Format(f event.Event) string {
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the GELF message to dst
+func (formatter GELFFormatter) AppendFormat(dst []byte, f event.Event) []byte {
+	return appendJSON(dst, func(stream *jsoniter.Stream) {
+		stream.WriteVal(formatter.gelfMessage(f))
+	})
+}
+
+func (formatter GELFFormatter) gelfMessage(f event.Event) map[string]interface{} {
+	message := fmt.Sprint(f.Messages...)
+
+	msg := make(map[string]interface{}, len(f.Fields.CustomFields)+len(f.Fields.GlobalFields)+len(f.TypedFields)+7)
+	for k, v := range f.Fields.CustomFields {
+		msg[gelfAdditionalField(k)] = v
+	}
+
+	for _, tf := range f.TypedFields {
+		msg[gelfAdditionalField(tf.Key)] = tf.Value()
+	}
+
+	for k, v := range f.Fields.GlobalFields {
+		if k == "stacktrace" {
+			continue
+		}
+		msg[gelfAdditionalField(k)] = v
+	}
+
+	shortMessage := strings.TrimSpace(message)
+	if pos := strings.IndexByte(shortMessage, '\n'); pos >= 0 {
+		shortMessage = shortMessage[:pos]
+	}
+
+	fullMessage := message
+	if stacktrace, ok := f.Fields.GlobalFields["stacktrace"]; ok {
+		fullMessage = fmt.Sprintf("%s\n%s", message, stacktrace)
+	}
+
+	if fullMessage != shortMessage {
+		msg["full_message"] = fullMessage
+	}
+
+	msg["version"] = "1.1"
+	msg["host"] = formatter.host
+	msg["short_message"] = shortMessage
+	msg["timestamp"] = float64(f.Time.UnixNano()/int64(1e6)) / 1e3
+	msg["level"] = syslogSeverity(f.Level)
+	msg["_module"] = f.Module
+	msg["_level_name"] = f.Level.GetLevelName()
+
+	return msg
+}
+
+// gelfAdditionalField convert the key to a valid additional field name, _id is reserved by GELF
+func gelfAdditionalField(key string) string {
+	key = "_" + gelfInvalidKeyChars.ReplaceAllString(key, "_")
+	if key == "_id" {
+		return "_id_"
+	}
+
+	return key
+}
@@ -0,0 +1,63 @@
+package formatter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGELFFormatter_Format(t *testing.T) {
+	f := formatter.NewGELFFormatter().Host("localhost")
+
+	tm := time.Date(2019, 7, 17, 16, 58, 24, 123456000, time.UTC)
+	res := f.Format(event.Event{
+		Time:   tm,
+		Module: "asteria.user",
+		Level:  level.Error,
+		Fields: event.Fields{
+			GlobalFields: map[string]interface{}{"file": "user.go", "line": 12},
+			CustomFields: map[string]interface{}{"uid": 134, "id": 1, "user name": "Tom"},
+		},
+		Messages: []interface{}{"Hello, world"},
+	})
+
+	assert.JSONEq(t, `{
+		"version": "1.1",
+		"host": "localhost",
+		"short_message": "Hello, world",
+		"timestamp": 1563382704.123,
+		"level": 3,
+		"_module": "asteria.user",
+		"_level_name": "ERROR",
+		"_file": "user.go",
+		"_line": 12,
+		"_uid": 134,
+		"_id_": 1,
+		"_user_name": "Tom"
+	}`, res)
+
+	res = f.Format(event.Event{
+		Time:   tm,
+		Module: "asteria",
+		Level:  level.Debug,
+		Fields: event.Fields{
+			GlobalFields: map[string]interface{}{"stacktrace": "goroutine 1"},
+		},
+		Messages: []interface{}{"Hello\nworld"},
+	})
+
+	assert.JSONEq(t, `{
+		"version": "1.1",
+		"host": "localhost",
+		"short_message": "Hello",
+		"full_message": "Hello\nworld\ngoroutine 1",
+		"timestamp": 1563382704.123,
+		"level": 7,
+		"_module": "asteria",
+		"_level_name": "DEBUG"
+	}`, res)
+}
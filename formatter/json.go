@@ -6,40 +6,219 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/mylxsw/asteria/event"
-	"github.com/mylxsw/asteria/level"
 )
 
 var json = jsoniter.ConfigFastest
 
-type jsonOutput struct {
-	ModuleName string                 `json:"module"`
-	LevelName  string                 `json:"level_name"`
-	Level      level.Level            `json:"level"`
-	Context    map[string]interface{} `json:"context"`
-	Message    string                 `json:"message"`
-	DateTime   string                 `json:"datetime"`
+// JSONKeys are the keys of JSONFormatter output, the empty keys are omitted
+type JSONKeys struct {
+	Module    string
+	LevelName string
+	Level     string
+	Context   string
+	Message   string
+	DateTime  string
 }
 
+// DefaultJSONKeys are the keys used by JSONFormatter by default
+var DefaultJSONKeys = JSONKeys{
+	Module:    "module",
+	LevelName: "level_name",
+	Level:     "level",
+	Context:   "context",
+	Message:   "message",
+	DateTime:  "datetime",
+}
+
+// JSONTimeFormat is the time encoding of JSONFormatter
+type JSONTimeFormat int
+
+const (
+	// JSONTimeRFC3339 is RFC3339 with second precision, it is the default
+	JSONTimeRFC3339 JSONTimeFormat = iota
+	// JSONTimeRFC3339Nano is RFC3339 with nanosecond precision
+	JSONTimeRFC3339Nano
+	// JSONTimeUnix is the unix timestamp in seconds, as number
+	JSONTimeUnix
+	// JSONTimeUnixMilli is the unix timestamp in milliseconds, as number
+	JSONTimeUnixMilli
+	// JSONTimeLayout is the custom layout set by TimeLayout
+	JSONTimeLayout
+)
+
 // JSONFormatter json输格式化
-type JSONFormatter struct{}
+type JSONFormatter struct {
+	keys         *JSONKeys
+	timeFormat   JSONTimeFormat
+	timeLayout   string
+	flatten      bool
+	globalPrefix *string
+}
 
 // NewJSONFormatter create a new json LogFormatter
 func NewJSONFormatter() *JSONFormatter {
 	return &JSONFormatter{}
 }
 
+// Keys rename the keys of output, the empty keys are omitted, default is DefaultJSONKeys
+func (formatter *JSONFormatter) Keys(keys JSONKeys) *JSONFormatter {
+	formatter.keys = &keys
+	return formatter
+}
+
+// TimeFormat set the time encoding, default is JSONTimeRFC3339
+func (formatter *JSONFormatter) TimeFormat(format JSONTimeFormat) *JSONFormatter {
+	formatter.timeFormat = format
+	return formatter
+}
+
+// TimeLayout set a custom layout for time
+func (formatter *JSONFormatter) TimeLayout(layout string) *JSONFormatter {
+	formatter.timeFormat = JSONTimeLayout
+	formatter.timeLayout = layout
+	return formatter
+}
+
+// FlattenFields set whether write fields to the top level instead of nesting them under context,
+// the fields conflicting with the keys of output are dropped
+func (formatter *JSONFormatter) FlattenFields(flatten bool) *JSONFormatter {
+	formatter.flatten = flatten
+	return formatter
+}
+
+// GlobalFieldPrefix set the prefix for GlobalFields (such as file, line), default is #, empty to drop it
+func (formatter *JSONFormatter) GlobalFieldPrefix(prefix string) *JSONFormatter {
+	formatter.globalPrefix = &prefix
+	return formatter
+}
+
 // Format 日志格式化
 func (formatter JSONFormatter) Format(f event.Event) string {
-	datetime := f.Time.Format(time.RFC3339)
-
-	res, _ := json.Marshal(jsonOutput{
-		DateTime:   datetime,
-		Message:    fmt.Sprint(f.Messages...),
-		Level:      f.Level,
-		ModuleName: f.Module,
-		LevelName:  f.Level.GetLevelName(),
-		Context:    f.Fields.ToMap(),
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the json formatted event to dst
+func (formatter JSONFormatter) AppendFormat(dst []byte, f event.Event) []byte {
+	return appendJSON(dst, func(stream *jsoniter.Stream) {
+		formatter.write(stream, f)
+	})
+}
+
+// appendJSON append the json written by fn to dst, using a pooled stream
+func appendJSON(dst []byte, fn func(stream *jsoniter.Stream)) []byte {
+	stream := json.BorrowStream(nil)
+	buf := stream.Buffer()
+	defer func() {
+		// the pooled stream must not hold dst
+		stream.SetBuffer(buf[:0])
+		json.ReturnStream(stream)
+	}()
+
+	stream.SetBuffer(dst)
+	fn(stream)
+
+	return stream.Buffer()
+}
+
+// write the event to stream, the fields (including typed fields) are appended to the buffer directly
+func (formatter JSONFormatter) write(stream *jsoniter.Stream, f event.Event) {
+	keys := DefaultJSONKeys
+	if formatter.keys != nil {
+		keys = *formatter.keys
+	}
+
+	more := false
+	field := func(key string) bool {
+		if key == "" {
+			return false
+		}
+
+		if more {
+			stream.WriteMore()
+		}
+		more = true
+
+		stream.WriteObjectField(key)
+		return true
+	}
+
+	stream.WriteObjectStart()
+	if field(keys.Module) {
+		stream.WriteString(f.Module)
+	}
+	if field(keys.LevelName) {
+		stream.WriteString(f.Level.GetLevelName())
+	}
+	if field(keys.Level) {
+		stream.WriteInt(int(f.Level))
+	}
+
+	if formatter.flatten {
+		reserved := []string{keys.Module, keys.LevelName, keys.Level, keys.Message, keys.DateTime}
+		formatter.writeFields(stream, f, func(key string) bool {
+			if strIn(key, reserved) {
+				return false
+			}
+
+			return field(key)
+		})
+	} else if field(keys.Context) {
+		stream.WriteObjectStart()
+		first := true
+		formatter.writeFields(stream, f, func(key string) bool {
+			if !first {
+				stream.WriteMore()
+			}
+			first = false
+
+			stream.WriteObjectField(key)
+			return true
+		})
+		stream.WriteObjectEnd()
+	}
+
+	if field(keys.Message) {
+		stream.WriteString(fmt.Sprint(f.Messages...))
+	}
+	if field(keys.DateTime) {
+		formatter.writeTime(stream, f.Time)
+	}
+	stream.WriteObjectEnd()
+}
+
+// writeFields write the value of CustomFields, GlobalFields (with prefix) and typed fields,
+// the field is written only if fn return true, fn should write the key.
+// Each key is written once, see event.Event.RangeFields for the precedence
+func (formatter JSONFormatter) writeFields(stream *jsoniter.Stream, f event.Event, fn func(key string) bool) {
+	prefix := "#"
+	if formatter.globalPrefix != nil {
+		prefix = *formatter.globalPrefix
+	}
+
+	f.RangeFields(prefix, nil, func(key string, value interface{}, typed int) {
+		if !fn(key) {
+			return
+		}
+
+		if typed >= 0 {
+			stream.SetBuffer(f.TypedFields[typed].AppendJSONValue(stream.Buffer()))
+		} else {
+			stream.WriteVal(value)
+		}
 	})
+}
 
-	return string(res)
+func (formatter JSONFormatter) writeTime(stream *jsoniter.Stream, t time.Time) {
+	switch formatter.timeFormat {
+	case JSONTimeRFC3339Nano:
+		stream.WriteString(t.Format(time.RFC3339Nano))
+	case JSONTimeUnix:
+		stream.WriteInt64(t.Unix())
+	case JSONTimeUnixMilli:
+		stream.WriteInt64(t.UnixNano() / int64(time.Millisecond))
+	case JSONTimeLayout:
+		stream.WriteString(t.Format(formatter.timeLayout))
+	default:
+		stream.WriteString(t.Format(time.RFC3339))
+	}
 }
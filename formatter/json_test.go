@@ -83,3 +83,78 @@ func TestJSONFormatter_Complex(t *testing.T) {
 
 	assert.NotEmpty(t, res)
 }
+
+func TestJSONFormatter_Options(t *testing.T) {
+	newEvent := func() event.Event {
+		return event.Event{
+			Time:   time.Date(2019, 7, 17, 17, 5, 4, 123456789, time.UTC),
+			Module: "asteria.user",
+			Level:  level.Error,
+			Fields: event.Fields{
+				GlobalFields: map[string]interface{}{"file": "user.go"},
+				CustomFields: map[string]interface{}{"user_id": 123, "message": "dropped"},
+			},
+			TypedFields: []event.Field{{Key: "name", Type: event.StringType, String: "Tom"}},
+			Messages:    []interface{}{"user created"},
+		}
+	}
+
+	var testCases = []struct {
+		formatter *formatter.JSONFormatter
+		expected  string
+	}{
+		{
+			formatter.NewJSONFormatter().TimeFormat(formatter.JSONTimeRFC3339Nano),
+			`{"module":"asteria.user","level_name":"ERROR","level":4,"context":{"user_id":123,"message":"dropped","#file":"user.go","name":"Tom"},"message":"user created","datetime":"2019-07-17T17:05:04.123456789Z"}`,
+		},
+		{
+			formatter.NewJSONFormatter().TimeFormat(formatter.JSONTimeUnix).GlobalFieldPrefix(""),
+			`{"module":"asteria.user","level_name":"ERROR","level":4,"context":{"user_id":123,"message":"dropped","file":"user.go","name":"Tom"},"message":"user created","datetime":1563383104}`,
+		},
+		{
+			formatter.NewJSONFormatter().TimeFormat(formatter.JSONTimeUnixMilli).Keys(formatter.JSONKeys{Message: "msg", DateTime: "ts", Context: "fields"}),
+			`{"fields":{"user_id":123,"message":"dropped","#file":"user.go","name":"Tom"},"msg":"user created","ts":1563383104123}`,
+		},
+		{
+			formatter.NewJSONFormatter().TimeLayout("2006-01-02 15:04:05").FlattenFields(true).GlobalFieldPrefix("").
+				Keys(formatter.JSONKeys{DateTime: "@timestamp", LevelName: "log.level", Module: "log.logger", Message: "message"}),
+			`{"log.logger":"asteria.user","log.level":"ERROR","user_id":123,"file":"user.go","name":"Tom","message":"user created","@timestamp":"2019-07-17 17:05:04"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.JSONEq(t, tc.expected, tc.formatter.Format(newEvent()))
+	}
+
+	// the keys are written in order
+	res := formatter.NewJSONFormatter().Keys(formatter.JSONKeys{DateTime: "@timestamp", Message: "message"}).Format(newEvent())
+	assert.Equal(t, `{"message":"user created","@timestamp":"2019-07-17T17:05:04Z"}`, res)
+}
+
+func TestJSONFormatter_DuplicateKeys(t *testing.T) {
+	newEvent := func() event.Event {
+		return event.Event{
+			Time:   time.Date(2019, 7, 17, 17, 5, 4, 0, time.UTC),
+			Module: "m",
+			Level:  level.Error,
+			Fields: event.Fields{
+				CustomFields: map[string]interface{}{"file": "custom"},
+				GlobalFields: map[string]interface{}{"file": "a.go"},
+			},
+			TypedFields: []event.Field{
+				{Key: "uid", Type: event.IntType, Integer: 1},
+				{Key: "uid", Type: event.IntType, Integer: 2},
+			},
+			Messages: []interface{}{"hello"},
+		}
+	}
+
+	// typed fields take precedence over custom fields, then global fields
+	res := formatter.NewJSONFormatter().FlattenFields(true).GlobalFieldPrefix("").Format(newEvent())
+	assert.Equal(t, `{"module":"m","level_name":"ERROR","level":4,"file":"custom","uid":2,"message":"hello","datetime":"2019-07-17T17:05:04Z"}`, res)
+
+	evt := newEvent()
+	evt.TypedFields = append(evt.TypedFields, event.Field{Key: "file", Type: event.StringType, String: "typed"})
+	res = formatter.NewJSONFormatter().Format(evt)
+	assert.Equal(t, `{"module":"m","level_name":"ERROR","level":4,"context":{"#file":"a.go","uid":2,"file":"typed"},"message":"hello","datetime":"2019-07-17T17:05:04Z"}`, res)
+}
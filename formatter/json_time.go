@@ -1,7 +1,6 @@
 package formatter
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/mylxsw/asteria/event"
@@ -17,16 +16,16 @@ func NewJSONWithTimeFormatter() *JSONWithTimeFormatter {
 
 // Format 日志格式化
 func (formatter JSONWithTimeFormatter) Format(f event.Event) string {
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the formatted event to dst
+func (formatter JSONWithTimeFormatter) AppendFormat(dst []byte, f event.Event) []byte {
 	datetime := f.Time.Format(time.RFC3339)
 
-	res, _ := json.Marshal(jsonOutput{
-		DateTime:   datetime,
-		Message:    fmt.Sprint(f.Messages...),
-		Level:      f.Level,
-		ModuleName: f.Module,
-		LevelName:  f.Level.GetLevelName(),
-		Context:    f.Fields.ToMap(),
-	})
+	dst = append(dst, '[')
+	dst = append(dst, datetime...)
+	dst = append(dst, "] "...)
 
-	return fmt.Sprintf("[%s] %s", datetime, string(res))
+	return JSONFormatter{}.AppendFormat(dst, f)
 }
@@ -0,0 +1,275 @@
+package formatter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mylxsw/asteria/event"
+)
+
+// LogfmtFormatter format the log as logfmt, such as
+//
+//	time=2019-07-17T17:05:04Z level=error module=asteria.user msg="user created" file=user.go line=12 user.id=123
+//
+// Nested fields are flattened with dotted keys, GlobalFields are written without # prefix,
+// keys of fields are sorted so that the output is deterministic. A key is written once, typed fields
+// take precedence over CustomFields, then GlobalFields, and the fields named as builtin keys
+// (time, level, module, msg) are renamed with fields. prefix
+type LogfmtFormatter struct {
+	timeFormat string
+	keyOrder   []string
+}
+
+// NewLogfmtFormatter create a new LogfmtFormatter
+func NewLogfmtFormatter() *LogfmtFormatter {
+	return &LogfmtFormatter{timeFormat: time.RFC3339}
+}
+
+// TimeFormat set the layout for time, default is time.RFC3339
+func (formatter *LogfmtFormatter) TimeFormat(layout string) *LogfmtFormatter {
+	formatter.timeFormat = layout
+	return formatter
+}
+
+// KeyOrder set the keys written first in the order given, such as KeyOrder("level", "msg", "request_id"),
+// the builtin keys (time, level, module, msg) not given follow them, then the other fields sorted by key
+func (formatter *LogfmtFormatter) KeyOrder(keys ...string) *LogfmtFormatter {
+	formatter.keyOrder = keys
+	return formatter
+}
+
+// Format 日志格式化
+func (formatter LogfmtFormatter) Format(f event.Event) string {
+	return formatString(formatter, f)
+}
+
+type logfmtPair struct {
+	key   string
+	value interface{}
+}
+
+// AppendFormat append the logfmt formatted event to dst
+func (formatter LogfmtFormatter) AppendFormat(dst []byte, f event.Event) []byte {
+	builtins := []logfmtPair{
+		{"time", f.Time.Format(formatter.timeFormat)},
+		{"level", strings.ToLower(f.Level.GetLevelName())},
+		{"module", f.Module},
+		{"msg", fmt.Sprint(f.Messages...)},
+	}
+
+	fields := logfmtFields(f, true)
+
+	written := make(map[string]bool, len(formatter.keyOrder))
+	first := true
+	write := func(p logfmtPair) {
+		if !first {
+			dst = append(dst, ' ')
+		}
+		first = false
+
+		dst = appendLogfmtKey(dst, p.key)
+		dst = append(dst, '=')
+		dst = appendLogfmtValue(dst, logfmtValue(p.value, formatter.timeFormat))
+	}
+
+	for _, key := range formatter.keyOrder {
+		if written[key] {
+			continue
+		}
+
+		for _, pairs := range [][]logfmtPair{builtins, fields} {
+			for _, p := range pairs {
+				if p.key == key {
+					write(p)
+				}
+			}
+		}
+
+		written[key] = true
+	}
+
+	for _, pairs := range [][]logfmtPair{builtins, fields} {
+		for _, p := range pairs {
+			if !written[p.key] {
+				write(p)
+			}
+		}
+	}
+
+	return dst
+}
+
+// logfmtFields return all fields of event flattened and sorted by key, each key is returned once,
+// typed fields take precedence over CustomFields, then GlobalFields. If rename is true, the fields named
+// as builtin keys are renamed with fields. prefix (such as msg to fields.msg) before deduplication,
+// a field named fields.msg wins over the renamed one from the same source
+func logfmtFields(f event.Event, rename bool) []logfmtPair {
+	fields := make([]logfmtPair, 0, len(f.Fields.CustomFields)+len(f.Fields.GlobalFields)+len(f.TypedFields))
+	index := make(map[string]int, cap(fields))
+
+	var flattened []logfmtPair
+	add := func(p logfmtPair) {
+		if i, ok := index[p.key]; ok {
+			fields[i] = p
+			return
+		}
+
+		index[p.key] = len(fields)
+		fields = append(fields, p)
+	}
+	merge := func() {
+		// the renamed fields are added first, so the fields with the same key override them
+		for _, p := range flattened {
+			if rename && logfmtBuiltin(p.key) {
+				add(logfmtPair{"fields." + p.key, p.value})
+			}
+		}
+		for _, p := range flattened {
+			if !rename || !logfmtBuiltin(p.key) {
+				add(p)
+			}
+		}
+		flattened = flattened[:0]
+	}
+
+	for k, v := range f.Fields.GlobalFields {
+		flattened = flattenLogfmtField(flattened, k, v)
+	}
+	merge()
+
+	for k, v := range f.Fields.CustomFields {
+		flattened = flattenLogfmtField(flattened, k, v)
+	}
+	merge()
+
+	for _, tf := range f.TypedFields {
+		flattened = flattenLogfmtField(flattened, tf.Key, tf.Value())
+		merge()
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+	return fields
+}
+
+// logfmtBuiltin return whether the key is written by LogfmtFormatter for the event itself
+func logfmtBuiltin(key string) bool {
+	return key == "time" || key == "level" || key == "module" || key == "msg"
+}
+
+// appendLogfmtFields append all fields of event as logfmt to dst
+func appendLogfmtFields(dst []byte, f event.Event) []byte {
+	for i, p := range logfmtFields(f, false) {
+		if i > 0 {
+			dst = append(dst, ' ')
+		}
+
+		dst = appendLogfmtKey(dst, p.key)
+		dst = append(dst, '=')
+		dst = appendLogfmtValue(dst, logfmtValue(p.value, time.RFC3339))
+	}
+
+	return dst
+}
+
+// flattenLogfmtField append the field to pairs, maps with string keys are flattened with dotted keys
+func flattenLogfmtField(pairs []logfmtPair, key string, value interface{}) []logfmtPair {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			pairs = flattenLogfmtField(pairs, key+"."+k, vv)
+		}
+		return pairs
+	case map[string]string:
+		for k, vv := range v {
+			pairs = append(pairs, logfmtPair{key + "." + k, vv})
+		}
+		return pairs
+	}
+
+	// named map types such as log.Fields
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		for _, k := range rv.MapKeys() {
+			pairs = flattenLogfmtField(pairs, key+"."+k.String(), rv.MapIndex(k).Interface())
+		}
+		return pairs
+	}
+
+	return append(pairs, logfmtPair{key, value})
+}
+
+// logfmtValue convert the value to text, time.Time is formatted with timeFormat
+func logfmtValue(value interface{}, timeFormat string) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case time.Time:
+		return v.Format(timeFormat)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case []byte:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// appendLogfmtKey append the key to dst, the characters not allowed in key are replaced with _
+func appendLogfmtKey(dst []byte, key string) []byte {
+	if key == "" {
+		return append(dst, '_')
+	}
+
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			dst = append(dst, '_')
+		} else {
+			dst = append(dst, c)
+		}
+	}
+
+	return dst
+}
+
+// appendLogfmtValue append the value to dst, it is quoted if empty or containing spaces, = or "
+func appendLogfmtValue(dst []byte, value string) []byte {
+	if !logfmtNeedsQuote(value) {
+		return append(dst, value...)
+	}
+
+	return strconv.AppendQuote(dst, value)
+}
+
+func logfmtNeedsQuote(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
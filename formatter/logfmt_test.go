@@ -0,0 +1,102 @@
+package formatter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/stretchr/testify/assert"
+)
+
+type logfmtFields map[string]interface{}
+
+func logfmtEvent() event.Event {
+	return event.Event{
+		Time:   time.Date(2019, 7, 17, 17, 5, 4, 0, time.UTC),
+		Module: "asteria.user",
+		Level:  level.Error,
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{
+				"user": logfmtFields{
+					"id":   123,
+					"name": "Tom Cat",
+					"role": map[string]interface{}{"name": "admin"},
+				},
+				"request_id": "abc",
+				"error":      errors.New(`file "a.log" not found`),
+				"empty":      "",
+				"ratio":      0.5,
+				"tags":       []string{"a", "b"},
+				"nil":        nil,
+			},
+			GlobalFields: map[string]interface{}{
+				"file":    "user.go",
+				"line":    12,
+				"package": "github.com/mylxsw/asteria",
+			},
+		},
+		TypedFields: []event.Field{{Key: "elapsed", Type: event.DurationType, Integer: int64(1500 * time.Millisecond)}},
+		Messages:    []interface{}{"user created\nsecond line"},
+	}
+}
+
+func TestLogfmtFormatter_Format(t *testing.T) {
+	f := formatter.NewLogfmtFormatter()
+
+	expected := `time=2019-07-17T17:05:04Z level=error module=asteria.user msg="user created\nsecond line" ` +
+		`elapsed=1.5s empty="" error="file \"a.log\" not found" file=user.go line=12 nil=null ` +
+		`package=github.com/mylxsw/asteria ratio=0.5 request_id=abc tags="[\"a\",\"b\"]" ` +
+		`user.id=123 user.name="Tom Cat" user.role.name=admin`
+
+	// the output is deterministic
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected, f.Format(logfmtEvent()))
+	}
+}
+
+func TestLogfmtFormatter_KeyOrder(t *testing.T) {
+	f := formatter.NewLogfmtFormatter().
+		TimeFormat("2006-01-02 15:04:05").
+		KeyOrder("level", "msg", "request_id", "user.id")
+
+	evt := logfmtEvent()
+	evt.Fields.CustomFields = map[string]interface{}{"request_id": "abc", "user": map[string]interface{}{"id": 123}, "b": 1, "a": "x=y"}
+	evt.Fields.GlobalFields = nil
+	evt.TypedFields = nil
+	evt.Messages = []interface{}{"user created"}
+
+	assert.Equal(
+		t,
+		`level=error msg="user created" request_id=abc user.id=123 time="2019-07-17 17:05:04" module=asteria.user a="x=y" b=1`,
+		f.Format(evt),
+	)
+}
+
+func TestLogfmtFormatter_DuplicateKeys(t *testing.T) {
+	f := formatter.NewLogfmtFormatter()
+
+	evt := logfmtEvent()
+	evt.Fields.CustomFields = map[string]interface{}{"file": "custom", "msg": "field", "uid": 1}
+	evt.Fields.GlobalFields = map[string]interface{}{"file": "a.go", "line": 12}
+	evt.TypedFields = []event.Field{{Key: "uid", Type: event.IntType, Integer: 2}, {Key: "level", Type: event.StringType, String: "typed"}}
+	evt.Messages = []interface{}{"user created"}
+
+	// typed fields take precedence over custom fields, then global fields, builtin keys are renamed
+	expected := `time=2019-07-17T17:05:04Z level=error module=asteria.user msg="user created" ` +
+		`fields.level=typed fields.msg=field file=custom line=12 uid=2`
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected, f.Format(evt))
+	}
+
+	// renamed fields are deduplicated with the fields of the same key
+	evt.Fields.CustomFields = map[string]interface{}{"msg": "a", "fields.msg": "b"}
+	evt.Fields.GlobalFields = map[string]interface{}{"fields.level": "global"}
+	expected = `time=2019-07-17T17:05:04Z level=error module=asteria.user msg="user created" ` +
+		`fields.level=typed fields.msg=b uid=2`
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected, f.Format(evt))
+	}
+}
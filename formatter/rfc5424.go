@@ -0,0 +1,207 @@
+package formatter
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+)
+
+// Facility is the syslog facility
+type Facility int
+
+// syslog facilities defined in RFC 5424
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFtp
+	FacilityNtp
+	FacilityAudit
+	FacilityAlert
+	FacilityClock
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+const rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// RFC5424Formatter format the log as RFC 5424 syslog message
+//
+// The module name is used as APP-NAME, CustomFields and GlobalFields (with # prefix) are written to STRUCTURED-DATA
+type RFC5424Formatter struct {
+	facility Facility
+	hostname string
+	procID   string
+	sdID     string
+}
+
+// NewRFC5424Formatter create a new RFC5424Formatter
+func NewRFC5424Formatter(facility Facility) *RFC5424Formatter {
+	hostname, _ := os.Hostname()
+	return &RFC5424Formatter{
+		facility: facility,
+		hostname: hostname,
+		procID:   strconv.Itoa(os.Getpid()),
+		sdID:     "asteria@32473",
+	}
+}
+
+// Hostname set the HOSTNAME field, default is os.Hostname()
+func (formatter *RFC5424Formatter) Hostname(hostname string) *RFC5424Formatter {
+	formatter.hostname = hostname
+	return formatter
+}
+
+// SDID set the SD-ID for STRUCTURED-DATA, default is asteria@32473
+func (formatter *RFC5424Formatter) SDID(sdID string) *RFC5424Formatter {
+	formatter.sdID = sdID
+	return formatter
+}
+
+// Format 日志格式化
+func (formatter RFC5424Formatter) Format(f event.Event) string {
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the RFC 5424 syslog message to dst
+func (formatter RFC5424Formatter) AppendFormat(dst []byte, f event.Event) []byte {
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(int(formatter.facility)*8+syslogSeverity(f.Level)), 10)
+	dst = append(dst, ">1 "...)
+	dst = f.Time.AppendFormat(dst, rfc5424TimeFormat)
+	dst = append(dst, ' ')
+	dst = append(dst, syslogHeaderField(formatter.hostname, 255)...)
+	dst = append(dst, ' ')
+	dst = append(dst, syslogHeaderField(f.Module, 48)...)
+	dst = append(dst, ' ')
+	dst = append(dst, syslogHeaderField(formatter.procID, 128)...)
+	dst = append(dst, " - "...)
+	dst = append(dst, formatter.structuredData(f.Fields, f.TypedFields)...)
+	dst = append(dst, ' ')
+
+	return append(dst, fmt.Sprint(f.Messages...)...)
+}
+
+func (formatter RFC5424Formatter) structuredData(fields event.Fields, typed []event.Field) string {
+	params := make(map[string]interface{}, len(fields.CustomFields)+len(fields.GlobalFields)+len(typed))
+	for k, v := range fields.CustomFields {
+		params[k] = v
+	}
+	for _, tf := range typed {
+		params[tf.Key] = tf.Value()
+	}
+	for k, v := range fields.GlobalFields {
+		if k == "stacktrace" {
+			continue
+		}
+		params["#"+k] = v
+	}
+
+	if len(params) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("[")
+	sb.WriteString(formatter.sdID)
+	for _, k := range keys {
+		sb.WriteString(" ")
+		sb.WriteString(syslogParamName(k))
+		sb.WriteString(`="`)
+		sb.WriteString(syslogParamValue(params[k]))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString("]")
+
+	return sb.String()
+}
+
+// syslogSeverity convert level to syslog severity, Emergency is 0 and Debug is 7
+func syslogSeverity(le level.Level) int {
+	if le < level.Emergency || le > level.Debug {
+		return 7
+	}
+
+	return int(le) - 1
+}
+
+// syslogHeaderField replace the invalid characters in header field, and truncate it to max length
+func syslogHeaderField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+
+	if value == "" {
+		return "-"
+	}
+
+	if len(value) > maxLen {
+		return value[:maxLen]
+	}
+
+	return value
+}
+
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if len(name) > 32 {
+		return name[:32]
+	}
+
+	return name
+}
+
+func syslogParamValue(value interface{}) string {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case fmt.Stringer:
+		str = v.String()
+	case error:
+		str = v.Error()
+	case nil:
+		str = ""
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		str = fmt.Sprint(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		str = string(encoded)
+	}
+
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(str)
+}
@@ -0,0 +1,37 @@
+package formatter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRFC5424Formatter_Format(t *testing.T) {
+	f := formatter.NewRFC5424Formatter(formatter.FacilityLocal0).Hostname("localhost")
+
+	tm := time.Date(2019, 7, 17, 16, 58, 24, 123456000, time.UTC)
+	res := f.Format(event.Event{
+		Time:   tm,
+		Module: "asteria.user",
+		Level:  level.Error,
+		Fields: event.Fields{
+			GlobalFields: map[string]interface{}{"line": 12, "stacktrace": "xxx"},
+			CustomFields: map[string]interface{}{"uid": 134, "name": `Tom "]\`},
+		},
+		Messages: []interface{}{"Hello, world"},
+	})
+
+	assert.Regexp(t, `^<131>1 2019-07-17T16:58:24.123456Z localhost asteria.user \d+ - `, res)
+	assert.Contains(t, res, ` [asteria@32473 #line="12" name="Tom \"\]\\" uid="134"] Hello, world`)
+
+	res = f.SDID("test@1").Format(event.Event{
+		Time:     tm,
+		Level:    level.Debug,
+		Messages: []interface{}{"Hello, world"},
+	})
+	assert.Regexp(t, `^<135>1 2019-07-17T16:58:24.123456Z localhost - \d+ - - Hello, world$`, res)
+}
@@ -0,0 +1,367 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mylxsw/asteria/color"
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/misc"
+)
+
+// TemplateFormatter format the log with a pattern, such as
+//
+//	{time:2006-01-02 15:04:05.000} {level:abbr|color} [{module:abbr}] {message} {fields:json}
+//
+// A placeholder is {name:arg|modifier|modifier...}, the arg and modifiers are optional
+//
+//	{time:layout}         time with layout (default RFC3339), or unix, unixmilli
+//	{level:name}          level name, abbr for abbreviation, lower for lower case name
+//	{module:abbr}         module name, abbr for abbreviation
+//	{message}             message
+//	{field:key}           value of a field, GlobalFields are used without # prefix
+//	{fields:json}         all fields as json (default) or logfmt, select them with only=a,b or exclude=a,b
+//
+// Modifiers are applied in order, except color which is always applied at last
+//
+//	width=N       pad with spaces or truncate to N characters
+//	pad=N         pad with spaces on the right to N characters
+//	lpad=N        pad with spaces on the left to N characters
+//	trunc=N       truncate to N characters
+//	upper, lower  change the case
+//	color         wrap with the color of level, or color=red for a fixed color
+//
+// Use {{ and }} for literal braces. The pattern is compiled when creating the formatter
+type TemplateFormatter struct {
+	pattern  string
+	segments []templateSegment
+}
+
+type templateSegment func(dst []byte, f event.Event) []byte
+
+// NewTemplateFormatter compile the pattern and create a new TemplateFormatter
+func NewTemplateFormatter(pattern string) (*TemplateFormatter, error) {
+	segments, err := compileTemplate(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TemplateFormatter{pattern: pattern, segments: segments}, nil
+}
+
+// MustTemplateFormatter is like NewTemplateFormatter but panics if the pattern is invalid
+func MustTemplateFormatter(pattern string) *TemplateFormatter {
+	formatter, err := NewTemplateFormatter(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return formatter
+}
+
+// Pattern return the pattern of formatter
+func (formatter TemplateFormatter) Pattern() string {
+	return formatter.pattern
+}
+
+// Format 日志格式化
+func (formatter TemplateFormatter) Format(f event.Event) string {
+	return formatString(formatter, f)
+}
+
+// AppendFormat append the formatted event to dst
+func (formatter TemplateFormatter) AppendFormat(dst []byte, f event.Event) []byte {
+	for _, seg := range formatter.segments {
+		dst = seg(dst, f)
+	}
+
+	return dst
+}
+
+func compileTemplate(pattern string) ([]templateSegment, error) {
+	segments := make([]templateSegment, 0)
+
+	var literal []byte
+	flush := func() {
+		if len(literal) > 0 {
+			text := string(literal)
+			segments = append(segments, func(dst []byte, f event.Event) []byte {
+				return append(dst, text...)
+			})
+			literal = nil
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '{' && i+1 < len(pattern) && pattern[i+1] == '{':
+			literal = append(literal, '{')
+			i++
+		case c == '}' && i+1 < len(pattern) && pattern[i+1] == '}':
+			literal = append(literal, '}')
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("unexpected } at %d in template %q", i, pattern)
+		case c == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed { at %d in template %q", i, pattern)
+			}
+
+			seg, err := compilePlaceholder(pattern[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid placeholder at %d in template %q: %v", i, pattern, err)
+			}
+
+			flush()
+			segments = append(segments, seg)
+			i += end
+		default:
+			literal = append(literal, c)
+		}
+	}
+
+	flush()
+	return segments, nil
+}
+
+type templateText func(f event.Event) string
+
+type templateModifier func(text string) string
+
+func compilePlaceholder(placeholder string) (templateSegment, error) {
+	parts := strings.Split(placeholder, "|")
+
+	name, arg := parts[0], ""
+	if pos := strings.IndexByte(name, ':'); pos >= 0 {
+		name, arg = name[:pos], name[pos+1:]
+	}
+
+	var only, exclude []string
+	var modifiers []templateModifier
+	var colorize func(f event.Event, text string) string
+
+	for _, m := range parts[1:] {
+		key, val := m, ""
+		if pos := strings.IndexByte(m, '='); pos >= 0 {
+			key, val = m[:pos], m[pos+1:]
+		}
+
+		switch key {
+		case "width", "pad", "lpad", "trunc":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%s requires a non-negative number", key)
+			}
+			modifiers = append(modifiers, widthModifier(key, n))
+		case "upper":
+			modifiers = append(modifiers, strings.ToUpper)
+		case "lower":
+			modifiers = append(modifiers, strings.ToLower)
+		case "color":
+			if val == "" {
+				colorize = func(f event.Event, text string) string {
+					return misc.LevelColorWrap(f.Level, text)
+				}
+				break
+			}
+
+			c, ok := templateColors[val]
+			if !ok {
+				return nil, fmt.Errorf("unknown color %s", val)
+			}
+			colorize = func(f event.Event, text string) string {
+				return color.TextWrap(c, text)
+			}
+		case "only":
+			only = strings.Split(val, ",")
+		case "exclude":
+			exclude = strings.Split(val, ",")
+		default:
+			return nil, fmt.Errorf("unknown modifier %s", m)
+		}
+	}
+
+	if (only != nil || exclude != nil) && name != "fields" {
+		return nil, fmt.Errorf("only and exclude are only supported by fields")
+	}
+
+	text, err := placeholderText(name, arg, only, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(dst []byte, f event.Event) []byte {
+		s := text(f)
+		for _, m := range modifiers {
+			s = m(s)
+		}
+
+		if colorize != nil {
+			s = colorize(f, s)
+		}
+
+		return append(dst, s...)
+	}, nil
+}
+
+func placeholderText(name string, arg string, only []string, exclude []string) (templateText, error) {
+	switch name {
+	case "time":
+		switch arg {
+		case "unix":
+			return func(f event.Event) string { return strconv.FormatInt(f.Time.Unix(), 10) }, nil
+		case "unixmilli":
+			return func(f event.Event) string { return strconv.FormatInt(f.Time.UnixNano()/int64(time.Millisecond), 10) }, nil
+		case "":
+			arg = time.RFC3339
+		}
+		return func(f event.Event) string { return f.Time.Format(arg) }, nil
+	case "level":
+		switch arg {
+		case "", "name":
+			return func(f event.Event) string { return f.Level.GetLevelName() }, nil
+		case "abbr":
+			return func(f event.Event) string { return f.Level.GetLevelNameAbbreviation() }, nil
+		case "lower":
+			return func(f event.Event) string { return strings.ToLower(f.Level.GetLevelName()) }, nil
+		}
+	case "module":
+		switch arg {
+		case "":
+			return func(f event.Event) string { return f.Module }, nil
+		case "abbr":
+			return func(f event.Event) string { return misc.ModuleNameAbbr(f.Module) }, nil
+		}
+	case "message":
+		if arg == "" {
+			return func(f event.Event) string { return fmt.Sprint(f.Messages...) }, nil
+		}
+	case "field":
+		if arg == "" {
+			return nil, fmt.Errorf("field requires a key, such as {field:user_id}")
+		}
+		return func(f event.Event) string { return fieldText(f, arg) }, nil
+	case "fields":
+		switch arg {
+		case "", "json":
+			return func(f event.Event) string {
+				return string(selectFields(f, only, exclude).AppendFieldsJSON(nil))
+			}, nil
+		case "logfmt":
+			return func(f event.Event) string {
+				return string(appendLogfmtFields(nil, selectFields(f, only, exclude)))
+			}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown placeholder %s", name)
+	}
+
+	return nil, fmt.Errorf("unsupported argument %s for %s", arg, name)
+}
+
+// fieldText return the value of field as text, typed fields take precedence over CustomFields and GlobalFields
+func fieldText(f event.Event, key string) string {
+	for i := len(f.TypedFields) - 1; i >= 0; i-- {
+		if f.TypedFields[i].Key == key {
+			return logfmtValue(f.TypedFields[i].Value(), time.RFC3339)
+		}
+	}
+
+	if v, ok := f.Fields.CustomFields[key]; ok {
+		return logfmtValue(v, time.RFC3339)
+	}
+
+	if v, ok := f.Fields.GlobalFields[key]; ok {
+		return logfmtValue(v, time.RFC3339)
+	}
+
+	return ""
+}
+
+// selectFields return an event only with the fields selected, GlobalFields are matched without # prefix
+func selectFields(f event.Event, only []string, exclude []string) event.Event {
+	selected := func(key string) bool {
+		if only != nil && !strIn(key, only) {
+			return false
+		}
+
+		return !strIn(key, exclude)
+	}
+
+	res := event.Event{
+		Fields: event.Fields{
+			CustomFields: make(map[string]interface{}, len(f.Fields.CustomFields)),
+			GlobalFields: make(map[string]interface{}, len(f.Fields.GlobalFields)),
+		},
+	}
+
+	for k, v := range f.Fields.CustomFields {
+		if selected(k) {
+			res.Fields.CustomFields[k] = v
+		}
+	}
+
+	for k, v := range f.Fields.GlobalFields {
+		if selected(k) {
+			res.Fields.GlobalFields[k] = v
+		}
+	}
+
+	for _, tf := range f.TypedFields {
+		if selected(tf.Key) {
+			res.TypedFields = append(res.TypedFields, tf)
+		}
+	}
+
+	return res
+}
+
+func widthModifier(kind string, n int) templateModifier {
+	return func(text string) string {
+		length := utf8.RuneCountInString(text)
+
+		if (kind == "width" || kind == "trunc") && length > n {
+			return string([]rune(text)[:n])
+		}
+
+		if length >= n {
+			return text
+		}
+
+		switch kind {
+		case "width", "pad":
+			return text + strings.Repeat(" ", n-length)
+		case "lpad":
+			return strings.Repeat(" ", n-length) + text
+		}
+
+		return text
+	}
+}
+
+var templateColors = map[string]color.Color{
+	"black":   color.Black,
+	"red":     color.Red,
+	"green":   color.Green,
+	"yellow":  color.Yellow,
+	"blue":    color.Blue,
+	"magenta": color.Magenta,
+	"cyan":    color.Cyan,
+	"white":   color.White,
+	"grey":    color.LightGrey,
+}
+
+func strIn(str string, strArray []string) bool {
+	for _, s := range strArray {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
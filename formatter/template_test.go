@@ -0,0 +1,69 @@
+package formatter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func templateEvent() event.Event {
+	return event.Event{
+		Time:   time.Date(2019, 7, 17, 17, 5, 4, 123000000, time.UTC),
+		Module: "asteria.user.jobs",
+		Level:  level.Error,
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{"user_id": 123},
+			GlobalFields: map[string]interface{}{"file": "user.go", "line": 12},
+		},
+		TypedFields: []event.Field{{Key: "name", Type: event.StringType, String: "Tom"}},
+		Messages:    []interface{}{"user created"},
+	}
+}
+
+func TestTemplateFormatter_Format(t *testing.T) {
+	var testCases = map[string]string{
+		"{time:2006-01-02 15:04:05.000} {level:abbr} [{module:abbr}] {message}": "2019-07-17 17:05:04.123 EROR [a.u.jobs] user created",
+		"{time} {time:unix} {time:unixmilli}":                                   "2019-07-17T17:05:04Z 1563383104 1563383104123",
+		"{level} {level:lower} {module}":                                        "ERROR error asteria.user.jobs",
+		"[{level|width=3}] [{module|width=20}] [{message|lpad=14}]":             "[ERR] [asteria.user.jobs   ] [  user created]",
+		"[{module|trunc=7|upper}] [{level:lower|pad=7}]":                        "[ASTERIA] [error  ]",
+		"{field:user_id} {field:file}:{field:line} {field:name} [{field:none}]": "123 user.go:12 Tom []",
+		"{fields:json|only=user_id,name}":                                       `{"user_id":123,"name":"Tom"}`,
+		"{fields:logfmt|exclude=user_id}":                                       `file=user.go line=12 name=Tom`,
+		"{{literal}} {message}":                                                 "{literal} user created",
+		"{level:abbr|color} {message|color=grey}":                               "\x1b[97;41mEROR\x1b[0m \x1b[90muser created\x1b[0m",
+		"{level|width=2|color}":                                                 "\x1b[97;41mER\x1b[0m",
+	}
+
+	for pattern, expected := range testCases {
+		f, err := formatter.NewTemplateFormatter(pattern)
+		assert.NoError(t, err, pattern)
+		assert.Equal(t, expected, f.Format(templateEvent()), pattern)
+		assert.Equal(t, pattern, f.Pattern())
+	}
+}
+
+func TestTemplateFormatter_Invalid(t *testing.T) {
+	var testCases = []string{
+		"{unknown}",
+		"{message",
+		"message}",
+		"{level:unknown}",
+		"{field}",
+		"{message|width=abc}",
+		"{message|color=pink}",
+		"{message|unknown}",
+		"{message|only=a}",
+	}
+
+	for _, pattern := range testCases {
+		_, err := formatter.NewTemplateFormatter(pattern)
+		assert.Error(t, err, pattern)
+	}
+
+	assert.Panics(t, func() { formatter.MustTemplateFormatter("{unknown}") })
+}
@@ -9,4 +9,5 @@ require (
 	github.com/stretchr/testify v1.4.0
 	golang.org/x/text v0.3.4
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -9,6 +9,7 @@ import (
 type ContextLogger struct {
 	logger  *AsteriaLogger
 	context Fields
+	typed   []Field
 }
 
 func (logger *ContextLogger) DebugEnabled() bool {
@@ -43,7 +44,15 @@ func (logger *ContextLogger) EmergencyEnabled() bool {
 	return logger.logger.EmergencyEnabled()
 }
 
+// With 添加 @ 字段, data of type Field or []Field is added as typed fields like Typed
 func (logger *ContextLogger) With(data interface{}) Logger {
+	switch fields := data.(type) {
+	case Field:
+		return logger.Typed(fields)
+	case []Field:
+		return logger.Typed(fields...)
+	}
+
 	return logger.WithFields(Fields{
 		"@": data,
 	})
@@ -70,69 +79,82 @@ func (logger *ContextLogger) WithFields(c Fields) Logger {
 	return &ContextLogger{
 		logger:  logger.logger,
 		context: c2,
+		typed:   logger.typed,
+	}
+}
+
+// Typed 带有类型化字段的日志输出, the fields are appended to the existing typed fields
+func (logger *ContextLogger) Typed(fields ...Field) Logger {
+	typed := make([]Field, 0, len(logger.typed)+len(fields))
+	typed = append(append(typed, logger.typed...), fields...)
+
+	return &ContextLogger{
+		logger:  logger.logger,
+		context: logger.context,
+		typed:   typed,
 	}
 }
 
 func (logger *ContextLogger) Emergency(v ...interface{}) {
-	logger.logger.Output(3, level.Emergency, logger.context, v...)
+	logger.logger.output(3, level.Emergency, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Alert(v ...interface{}) {
-	logger.logger.Output(3, level.Alert, logger.context, v...)
+	logger.logger.output(3, level.Alert, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Critical(v ...interface{}) {
-	logger.logger.Output(3, level.Critical, logger.context, v...)
+	logger.logger.output(3, level.Critical, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Error(v ...interface{}) {
-	logger.logger.Output(3, level.Error, logger.context, v...)
+	logger.logger.output(3, level.Error, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Warning(v ...interface{}) {
-	logger.logger.Output(3, level.Warning, logger.context, v...)
+	logger.logger.output(3, level.Warning, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Notice(v ...interface{}) {
-	logger.logger.Output(3, level.Notice, logger.context, v...)
+	logger.logger.output(3, level.Notice, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Info(v ...interface{}) {
-	logger.logger.Output(3, level.Info, logger.context, v...)
+	logger.logger.output(3, level.Info, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Debug(v ...interface{}) {
-	logger.logger.Output(3, level.Debug, logger.context, v...)
+	logger.logger.output(3, level.Debug, logger.context, logger.typed, v...)
 }
 
 func (logger *ContextLogger) Emergencyf(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Emergency, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Emergency, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Alertf(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Alert, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Alert, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Criticalf(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Critical, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Critical, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Errorf(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Error, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Error, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Warningf(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Warning, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Warning, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Noticef(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Notice, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Notice, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Infof(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Info, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Info, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
 
 func (logger *ContextLogger) Debugf(format string, v ...interface{}) {
-	logger.logger.Output(3, level.Debug, logger.context, fmt.Sprintf(format, v...))
+	logger.logger.output(3, level.Debug, logger.context, logger.typed, fmt.Sprintf(format, v...))
 }
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"context"
+)
+
+// ContextExtractor extract fields from context.Context, such as request id, user id or trace id
+type ContextExtractor func(ctx context.Context, fields Fields)
+
+type loggerContextKey struct{}
+
+// AddContextExtractor add extractors which will be used by WithContext
+func AddContextExtractor(extractors ...ContextExtractor) {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	defaultLogConfig.ContextExtractors = append(defaultLogConfig.ContextExtractors, extractors...)
+}
+
+// ContextExtractors return all context extractors
+func ContextExtractors() []ContextExtractor {
+	moduleLock.RLock()
+	defer moduleLock.RUnlock()
+
+	return defaultLogConfig.ContextExtractors
+}
+
+// ContextFields extract fields from context using all context extractors
+func ContextFields(ctx context.Context) Fields {
+	fields := Fields{}
+	if ctx == nil {
+		return fields
+	}
+
+	for _, extractor := range ContextExtractors() {
+		extractor(ctx, fields)
+	}
+
+	return fields
+}
+
+// ContextValueExtractor create an extractor which write the value of key in context to field
+func ContextValueExtractor(key interface{}, field string) ContextExtractor {
+	return func(ctx context.Context, fields Fields) {
+		if val := ctx.Value(key); val != nil {
+			fields[field] = val
+		}
+	}
+}
+
+// TraceExtractor create an extractor which write trace id and span id to trace_id and span_id fields
+//
+// For OpenTelemetry, the fn can be
+//
+//	func(ctx context.Context) (string, string, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return sc.TraceID().String(), sc.SpanID().String(), sc.IsValid()
+//	}
+func TraceExtractor(fn func(ctx context.Context) (traceID string, spanID string, ok bool)) ContextExtractor {
+	return func(ctx context.Context, fields Fields) {
+		if traceID, spanID, ok := fn(ctx); ok {
+			fields["trace_id"] = traceID
+			fields["span_id"] = spanID
+		}
+	}
+}
+
+// NewContext return a copy of ctx which carries the logger
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext return the logger stored in ctx
+func FromContext(ctx context.Context) (Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	logger, ok := ctx.Value(loggerContextKey{}).(Logger)
+	return logger, ok
+}
+
+// contextLogger is the logger which add fields extracted from ctx by itself, such as AsteriaLogger and ContextLogger
+type contextLogger interface {
+	WithContext(ctx context.Context) Logger
+}
+
+// Ctx return the logger stored in ctx (or the default logger if not exist) with fields extracted from ctx
+func Ctx(ctx context.Context) Logger {
+	if logger, ok := FromContext(ctx); ok {
+		if cl, ok := logger.(contextLogger); ok {
+			return cl.WithContext(ctx)
+		}
+
+		return logger.WithFields(ContextFields(ctx))
+	}
+
+	return Default().WithContext(ctx)
+}
+
+// WithContext return the default logger with fields extracted from ctx
+func WithContext(ctx context.Context) Logger {
+	return Default().WithContext(ctx)
+}
+
+// WithContext return a logger with fields extracted from ctx
+func (module *AsteriaLogger) WithContext(ctx context.Context) Logger {
+	return module.WithFields(ContextFields(ctx))
+}
+
+// WithContext return a logger with fields extracted from ctx, the existing fields take precedence
+func (logger *ContextLogger) WithContext(ctx context.Context) Logger {
+	c2 := ContextFields(ctx)
+	for k, v := range logger.context {
+		c2[k] = v
+	}
+
+	return &ContextLogger{
+		logger:  logger.logger,
+		context: c2,
+		typed:   logger.typed,
+	}
+}
@@ -0,0 +1,78 @@
+package log_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type requestIDKey struct{}
+
+// customLogger is a Logger implemented outside of log package, without WithContext
+type customLogger struct {
+	log.Logger
+}
+
+func TestWithContext(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogFormatter(formatter.NewDefaultFormatter(false))
+	log.DefaultLogWriter(mockWriter)
+
+	log.AddContextExtractor(
+		log.ContextValueExtractor(requestIDKey{}, "request_id"),
+		log.TraceExtractor(func(ctx context.Context) (string, string, bool) {
+			return "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true
+		}),
+	)
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+
+	log.WithContext(ctx).Info("hello")
+	assert.Regexp(t, regexp.MustCompile(`"request_id":"abc"`), mockWriter.LastMessage)
+	assert.Regexp(t, regexp.MustCompile(`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`), mockWriter.LastMessage)
+	assert.Regexp(t, regexp.MustCompile(`"span_id":"00f067aa0ba902b7"`), mockWriter.LastMessage)
+
+	log.Module("test").WithFields(log.Fields{"request_id": "def", "user_id": 123}).(*log.ContextLogger).WithContext(ctx).Info("hello")
+	assert.Regexp(t, regexp.MustCompile(`"request_id":"def"`), mockWriter.LastMessage)
+	assert.Regexp(t, regexp.MustCompile(`"user_id":123`), mockWriter.LastMessage)
+	assert.Regexp(t, regexp.MustCompile(`"trace_id"`), mockWriter.LastMessage)
+
+	log.Module("test").WithContext(context.Background()).Info("hello")
+	assert.NotRegexp(t, regexp.MustCompile(`"request_id"`), mockWriter.LastMessage)
+}
+
+func TestCtx(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogFormatter(formatter.NewDefaultFormatter(false))
+	log.DefaultLogWriter(mockWriter)
+	log.AddContextExtractor(log.ContextValueExtractor(requestIDKey{}, "request_id"))
+
+	_, ok := log.FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+	log.Ctx(ctx).Info("hello")
+	assert.Regexp(t, regexp.MustCompile(`^\[.*?\] INFO main hello {"request_id":"abc"}`), mockWriter.LastMessage)
+
+	ctx = log.NewContext(ctx, log.Module("test").WithFields(log.Fields{"user_id": 123}))
+	logger, ok := log.FromContext(ctx)
+	assert.True(t, ok)
+	assert.NotNil(t, logger)
+
+	log.Ctx(ctx).Info("hello")
+	assert.Regexp(t, regexp.MustCompile(`^\[.*?\] INFO test hello {.*?"request_id":"abc".*?}`), mockWriter.LastMessage)
+	assert.Regexp(t, regexp.MustCompile(`"user_id":123`), mockWriter.LastMessage)
+
+	// the fields are added by WithFields if the logger stored has no WithContext
+	ctx = log.NewContext(ctx, customLogger{log.Module("custom")})
+	log.Ctx(ctx).Info("hello")
+	assert.Regexp(t, regexp.MustCompile(`^\[.*?\] INFO custom hello {"request_id":"abc"}`), mockWriter.LastMessage)
+}
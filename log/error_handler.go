@@ -0,0 +1,103 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/writer"
+)
+
+// ErrorHandler handle the error returned by writer, message is the formatted event
+type ErrorHandler func(evt event.Event, w writer.Writer, message string, err error)
+
+// PanicErrorHandler panic when failed to write, it is the default ErrorHandler
+func PanicErrorHandler(evt event.Event, w writer.Writer, message string, err error) {
+	panic(fmt.Sprintf("can not write to output: %s", err))
+}
+
+// StderrErrorHandler write the error and the message to stderr
+func StderrErrorHandler(evt event.Event, w writer.Writer, message string, err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "can not write to output %T: %s\n%s\n", w, err, message)
+}
+
+// DropErrorHandler drop the messages failed to write, and count them
+type DropErrorHandler struct {
+	dropped uint64
+}
+
+// NewDropErrorHandler create a new DropErrorHandler
+func NewDropErrorHandler() *DropErrorHandler {
+	return &DropErrorHandler{}
+}
+
+// Handle is the ErrorHandler
+func (h *DropErrorHandler) Handle(evt event.Event, w writer.Writer, message string, err error) {
+	atomic.AddUint64(&h.dropped, 1)
+}
+
+// Dropped return the count of messages dropped
+func (h *DropErrorHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// RetryErrorHandler retry to write the message for n times, fallback is called if all retries failed
+func RetryErrorHandler(n int, fallback ErrorHandler) ErrorHandler {
+	return func(evt event.Event, w writer.Writer, message string, err error) {
+		for i := 0; i < n; i++ {
+			if err = write(w, evt, []byte(message)); err == nil {
+				return
+			}
+		}
+
+		if fallback != nil {
+			fallback(evt, w, message, err)
+		}
+	}
+}
+
+// FailoverErrorHandler write the message to secondary writer, fallback is called if secondary writer failed too
+func FailoverErrorHandler(secondary writer.Writer, fallback ErrorHandler) ErrorHandler {
+	return func(evt event.Event, w writer.Writer, message string, err error) {
+		if err := write(secondary, evt, []byte(message)); err != nil && fallback != nil {
+			fallback(evt, secondary, message, err)
+		}
+	}
+}
+
+// DefaultErrorHandler set the default ErrorHandler for modules which have no ErrorHandler set
+func DefaultErrorHandler(h ErrorHandler) {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	defaultLogConfig.ErrorHandler = h
+}
+
+// ErrorHandler set the ErrorHandler for module, nil means using the default ErrorHandler
+func (module *AsteriaLogger) ErrorHandler(h ErrorHandler) *AsteriaLogger {
+	module.lock.Lock()
+	defer module.lock.Unlock()
+
+	module.errorHandler = h
+	return module
+}
+
+func (module *AsteriaLogger) getErrorHandler() ErrorHandler {
+	module.lock.RLock()
+	h := module.errorHandler
+	module.lock.RUnlock()
+
+	if h != nil {
+		return h
+	}
+
+	moduleLock.RLock()
+	defer moduleLock.RUnlock()
+
+	if defaultLogConfig.ErrorHandler != nil {
+		return defaultLogConfig.ErrorHandler
+	}
+
+	return PanicErrorHandler
+}
@@ -0,0 +1,93 @@
+package log_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+type FlakyWriter struct {
+	failures int
+	MockWriter
+}
+
+func (w *FlakyWriter) Write(le level.Level, module string, message string) error {
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("temporary error")
+	}
+
+	return w.MockWriter.Write(le, module, message)
+}
+
+func TestErrorHandler(t *testing.T) {
+	log.Reset()
+	log.DefaultLogWriter(&ErrorWriter{})
+
+	var handledEvent event.Event
+	var handledWriter writer.Writer
+	var handledErr error
+
+	log.DefaultErrorHandler(func(evt event.Event, w writer.Writer, message string, err error) {
+		handledEvent, handledWriter, handledErr = evt, w, err
+	})
+
+	assert.NotPanics(t, func() { log.Module("asteria.user").Error("hello") })
+	assert.Equal(t, "asteria.user", handledEvent.Module)
+	assert.Equal(t, level.Error, handledEvent.Level)
+	assert.IsType(t, &ErrorWriter{}, handledWriter)
+	assert.EqualError(t, handledErr, "has some error")
+
+	// error handler for module
+	dropper := log.NewDropErrorHandler()
+	log.Module("asteria.user").ErrorHandler(dropper.Handle).Error("hello")
+	log.Module("asteria.user").Error("hello")
+	assert.Equal(t, uint64(2), dropper.Dropped())
+
+	log.Module("asteria.user").ErrorHandler(log.PanicErrorHandler)
+	assert.Panics(t, func() { log.Module("asteria.user").Error("hello") })
+
+	log.Module("asteria.user").ErrorHandler(log.StderrErrorHandler)
+	assert.NotPanics(t, func() { log.Module("asteria.user").Error("hello") })
+}
+
+func TestRetryErrorHandler(t *testing.T) {
+	log.Reset()
+
+	flakyWriter := &FlakyWriter{failures: 2}
+	dropper := log.NewDropErrorHandler()
+	logger := log.Module("asteria.user").
+		Writer(flakyWriter).
+		ErrorHandler(log.RetryErrorHandler(2, dropper.Handle))
+
+	logger.Info("hello")
+	assert.Equal(t, 1, flakyWriter.WriteCount)
+	assert.Equal(t, uint64(0), dropper.Dropped())
+
+	flakyWriter.failures = 3
+	logger.Info("hello")
+	assert.Equal(t, 1, flakyWriter.WriteCount)
+	assert.Equal(t, uint64(1), dropper.Dropped())
+}
+
+func TestFailoverErrorHandler(t *testing.T) {
+	log.Reset()
+
+	secondary := &MockWriter{}
+	dropper := log.NewDropErrorHandler()
+	logger := log.Module("asteria.user").
+		Writer(&ErrorWriter{}).
+		ErrorHandler(log.FailoverErrorHandler(secondary, dropper.Handle))
+
+	logger.Info("hello")
+	assert.Equal(t, 1, secondary.WriteCount)
+	assert.Equal(t, level.Info, secondary.LastLevel)
+
+	logger.ErrorHandler(log.FailoverErrorHandler(&ErrorWriter{}, dropper.Handle)).Info("hello")
+	assert.Equal(t, uint64(1), dropper.Dropped())
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+)
+
+// Field is a typed field, use it with Typed to avoid building Fields map on every log call
+type Field = event.Field
+
+// String create a string field
+func String(key string, val string) Field {
+	return Field{Key: key, Type: event.StringType, String: val}
+}
+
+// Int create an int field
+func Int(key string, val int) Field {
+	return Field{Key: key, Type: event.IntType, Integer: int64(val)}
+}
+
+// Int64 create an int64 field
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Type: event.IntType, Integer: val}
+}
+
+// Float64 create a float64 field
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Type: event.FloatType, Float: val}
+}
+
+// Bool create a bool field
+func Bool(key string, val bool) Field {
+	var i int64
+	if val {
+		i = 1
+	}
+
+	return Field{Key: key, Type: event.BoolType, Integer: i}
+}
+
+// Duration create a time.Duration field, it is written as nanoseconds like json.Marshal
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Type: event.DurationType, Integer: int64(val)}
+}
+
+// Time create a time.Time field
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, Type: event.TimeType, Integer: val.UnixNano(), Interface: val.Location()}
+}
+
+// Err create a field with key error, the value is the message of err
+func Err(err error) Field {
+	return Field{Key: "error", Type: event.ErrorType, Interface: err}
+}
+
+// Any create a field with any value, it is marshaled to json when writing, use the typed constructors if possible
+func Any(key string, val interface{}) Field {
+	return Field{Key: key, Type: event.AnyType, Interface: val}
+}
+
+// Typed 带有类型化字段的日志输出
+func Typed(fields ...Field) Logger {
+	return Default().Typed(fields...)
+}
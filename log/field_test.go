@@ -0,0 +1,72 @@
+package log_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTyped(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+	log.DefaultLogWriter(mockWriter)
+
+	log.Typed(
+		log.String("name", "Tom"),
+		log.Int("age", 18),
+		log.Float64("score", 99.5),
+		log.Bool("admin", true),
+		log.Duration("elapsed", 1500*time.Millisecond),
+		log.Err(errors.New("not found")),
+		log.Any("roles", []string{"admin"}),
+	).Error("user created")
+
+	var rs map[string]interface{}
+	assert.NoError(t, jsoniter.Unmarshal([]byte(mockWriter.LastMessage), &rs))
+	assert.Equal(t, "user created", rs["message"])
+
+	context := rs["context"].(map[string]interface{})
+	assert.Equal(t, "Tom", context["name"])
+	assert.Equal(t, float64(18), context["age"])
+	assert.Equal(t, 99.5, context["score"])
+	assert.Equal(t, true, context["admin"])
+	assert.Equal(t, float64(1500*time.Millisecond), context["elapsed"])
+	assert.Equal(t, "not found", context["error"])
+	assert.Equal(t, []interface{}{"admin"}, context["roles"])
+	assert.Contains(t, context["#file"], "field_test.go")
+
+	// typed fields are kept along with map fields
+	log.Module("test").Typed(log.String("name", "Tom")).WithFields(log.Fields{"user_id": 123}).With(log.Int("age", 18)).Info("hello")
+	assert.NoError(t, jsoniter.Unmarshal([]byte(mockWriter.LastMessage), &rs))
+	assert.Equal(t, map[string]interface{}{"name": "Tom", "user_id": float64(123), "age": float64(18)}, rs["context"])
+}
+
+func TestWithTypedFields(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+	log.DefaultLogWriter(mockWriter)
+
+	var rs map[string]interface{}
+
+	log.With(log.String("name", "Tom")).Info("hello")
+	assert.NoError(t, jsoniter.Unmarshal([]byte(mockWriter.LastMessage), &rs))
+	assert.Equal(t, map[string]interface{}{"name": "Tom"}, rs["context"])
+
+	log.Module("test").With([]log.Field{log.String("name", "Tom"), log.Int("age", 18)}).With(log.Bool("admin", true)).Info("hello")
+	assert.NoError(t, jsoniter.Unmarshal([]byte(mockWriter.LastMessage), &rs))
+	assert.Equal(t, map[string]interface{}{"name": "Tom", "age": float64(18), "admin": true}, rs["context"])
+
+	// other values are still written to the @ field
+	log.With("hello").Info("hello")
+	assert.NoError(t, jsoniter.Unmarshal([]byte(mockWriter.LastMessage), &rs))
+	assert.Equal(t, map[string]interface{}{"@": "hello"}, rs["context"])
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/mylxsw/asteria/filter"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+)
+
+// settings is the settings which can be inherited by descendants, zero value means not set
+type settings struct {
+	level     level.Level
+	formatter formatter.Formatter
+	writer    writer.Writer
+	filters   []filter.Chain
+}
+
+// ClearLogLevel clear the level set for this logger, it will fallback to the parent
+func (module *AsteriaLogger) ClearLogLevel() *AsteriaLogger {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	module.own.level = 0
+	loggers.resolveDescendants(module.moduleName)
+
+	return module
+}
+
+// ClearFormatter clear the formatter set for this logger, it will fallback to the parent
+func (module *AsteriaLogger) ClearFormatter() *AsteriaLogger {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	module.own.formatter = nil
+	loggers.resolveDescendants(module.moduleName)
+
+	return module
+}
+
+// ClearWriter clear the writer set for this logger, it will fallback to the parent
+func (module *AsteriaLogger) ClearWriter() *AsteriaLogger {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	module.own.writer = nil
+	loggers.resolveDescendants(module.moduleName)
+
+	return module
+}
+
+// ClearFilters clear the filters added to this logger, filters inherited from parents are kept
+func (module *AsteriaLogger) ClearFilters() *AsteriaLogger {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	module.own.filters = nil
+	loggers.resolveDescendants(module.moduleName)
+
+	return module
+}
+
+// Parent return the module name of parent, false if the module has no parent
+//
+// For example, the parent of asteria.user.jobs is asteria.user
+func Parent(moduleName string) (string, bool) {
+	pos := strings.LastIndex(moduleName, ".")
+	if pos <= 0 {
+		return "", false
+	}
+
+	return moduleName[:pos], true
+}
+
+// resolve compute the effective settings for all loggers, moduleLock must be held
+func (loggers Loggers) resolve() {
+	for _, l := range loggers {
+		l.resolve()
+	}
+}
+
+// resolveDescendants compute the effective settings for the logger and its descendants, moduleLock must be held
+func (loggers Loggers) resolveDescendants(moduleName string) {
+	prefix := moduleName + "."
+	for name, l := range loggers {
+		if name == moduleName || strings.HasPrefix(name, prefix) {
+			l.resolve()
+		}
+	}
+}
+
+// resolve compute the effective settings of the logger, moduleLock must be held
+//
+// Each setting is looked up from the logger itself, the rules matching it, then its ancestors (and the rules
+// matching them), and the DefaultConfig at last.
+// Filters are accumulated, the filters of ancestors run before the filters of descendants
+func (module *AsteriaLogger) resolve() {
+	var res settings
+	var filters [][]filter.Chain
+
+	for name, ok := module.moduleName, true; ok; name, ok = Parent(name) {
+		if l, exist := loggers[name]; exist {
+			res.merge(l.own)
+			if len(l.own.filters) > 0 {
+				filters = append(filters, l.own.filters)
+			}
+		}
+
+		for _, rule := range defaultLogConfig.Rules {
+			if rule.Match(name) {
+				res.merge(rule.own)
+			}
+		}
+	}
+
+	res.merge(settings{
+		level:     defaultLogConfig.LogLevel,
+		formatter: defaultLogConfig.LogFormatter,
+		writer:    defaultLogConfig.LogWriter,
+	})
+
+	for i := len(filters) - 1; i >= 0; i-- {
+		res.filters = append(res.filters, filters[i]...)
+	}
+
+	module.lock.Lock()
+	defer module.lock.Unlock()
+
+	module.level = res.level
+	module.formatter = res.formatter
+	module.writer = res.writer
+	module.filters = res.filters
+}
+
+// merge fill the settings not set with the values from s
+func (res *settings) merge(s settings) {
+	if res.level == 0 {
+		res.level = s.level
+	}
+	if res.formatter == nil {
+		res.formatter = s.formatter
+	}
+	if res.writer == nil {
+		res.writer = s.writer
+	}
+}
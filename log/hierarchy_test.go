@@ -0,0 +1,191 @@
+package log_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/filter"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParent(t *testing.T) {
+	parent, ok := log.Parent("asteria.user.jobs")
+	assert.True(t, ok)
+	assert.Equal(t, "asteria.user", parent)
+
+	_, ok = log.Parent("asteria")
+	assert.False(t, ok)
+}
+
+func TestHierarchyLevel(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogWriter(mockWriter)
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+	log.DefaultLogLevel(level.Info)
+
+	current := log.Module("asteria.user.jobs")
+	assert.False(t, current.DebugEnabled())
+
+	log.Module("asteria.user").LogLevel(level.Debug)
+
+	// current and future descendants inherit the level
+	assert.True(t, current.DebugEnabled())
+	assert.True(t, log.Module("asteria.user.enterprise.jobs").DebugEnabled())
+	assert.False(t, log.Module("asteria.users").DebugEnabled())
+	assert.False(t, log.Module("asteria").DebugEnabled())
+
+	// override by descendant
+	current.LogLevel(level.Error)
+	assert.False(t, current.WarningEnabled())
+	log.Module("asteria.user").LogLevel(level.Info)
+	assert.False(t, current.WarningEnabled())
+	assert.False(t, log.Module("asteria.user.enterprise.jobs").DebugEnabled())
+
+	// clear the override will fallback to parent
+	current.ClearLogLevel()
+	assert.True(t, current.InfoEnabled())
+	assert.False(t, current.DebugEnabled())
+
+	log.Module("asteria.user").ClearLogLevel()
+	log.DefaultLogLevel(level.Warning)
+	assert.False(t, current.InfoEnabled())
+
+	// Loggers.LogLevel clear all overrides
+	current.LogLevel(level.Debug)
+	log.All().LogLevel(level.Error)
+	assert.False(t, current.DebugEnabled())
+}
+
+func TestDefaultsApplyToExistingModules(t *testing.T) {
+	log.Reset()
+
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+	log.DefaultLogLevel(level.Info)
+
+	inherited := log.Module("asteria.defaults")
+	overridden := log.Module("asteria.defaults.override").LogLevel(level.Error)
+	overriddenWriter := &MockWriter{}
+	overridden.Writer(overriddenWriter)
+
+	defaultWriter := &MockWriter{}
+	log.DefaultLogWriter(defaultWriter)
+	log.DefaultLogLevel(level.Debug)
+	log.DefaultLogFormatter(formatter.NewDefaultFormatter(false))
+
+	// modules created before follow the new defaults
+	assert.True(t, inherited.DebugEnabled())
+	inherited.Debug("hello")
+	assert.Equal(t, 1, defaultWriter.WriteCount)
+	assert.NotContains(t, defaultWriter.LastMessage, `"message":"hello"`)
+
+	// unless they have their own settings
+	assert.False(t, overridden.WarningEnabled())
+	overridden.Error("hello")
+	assert.Equal(t, 1, defaultWriter.WriteCount)
+	assert.Equal(t, 1, overriddenWriter.WriteCount)
+	assert.NotContains(t, overriddenWriter.LastMessage, `"message":"hello"`)
+}
+
+func TestHierarchyWriterFormatter(t *testing.T) {
+	log.Reset()
+
+	defaultWriter := &MockWriter{}
+	log.DefaultLogWriter(defaultWriter)
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+
+	userWriter := &MockWriter{}
+	log.Module("asteria.user").Writer(userWriter).Formatter(formatter.NewDefaultFormatter(false))
+
+	log.Module("asteria.user.jobs").Info("hello")
+	assert.Equal(t, 1, userWriter.WriteCount)
+	assert.Equal(t, 0, defaultWriter.WriteCount)
+	assert.NotContains(t, userWriter.LastMessage, `"message":"hello"`)
+
+	log.Module("asteria.user.jobs").ClearFormatter()
+	log.Module("asteria.user").ClearFormatter().ClearWriter()
+	log.Module("asteria.user.jobs").Info("hello")
+	assert.Equal(t, 1, userWriter.WriteCount)
+	assert.Equal(t, 1, defaultWriter.WriteCount)
+	assert.Contains(t, defaultWriter.LastMessage, `"message":"hello"`)
+}
+
+func TestHierarchyFilters(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.DefaultLogWriter(mockWriter)
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+
+	var calls []string
+	recordFilter := func(name string) filter.Chain {
+		return func(filter filter.Filter) filter.Filter {
+			return func(f event.Event) {
+				calls = append(calls, name)
+				filter(f)
+			}
+		}
+	}
+
+	jobs := log.Module("asteria.user.jobs")
+	jobs.AddFilter(recordFilter("jobs"))
+	log.Module("asteria").AddFilter(recordFilter("asteria"))
+	log.Module("asteria.user").AddFilter(recordFilter("user"))
+
+	jobs.Info("hello")
+	assert.Equal(t, []string{"asteria", "user", "jobs"}, calls)
+	assert.Len(t, jobs.Filters(), 3)
+
+	calls = nil
+	log.Module("asteria.user").ClearFilters()
+	jobs.ClearFilters().Info("hello")
+	assert.Equal(t, []string{"asteria"}, calls)
+}
+
+type slowWriter struct {
+	MockWriter
+	started chan struct{}
+	done    int32
+}
+
+func (w *slowWriter) Write(le level.Level, module string, message string) error {
+	close(w.started)
+	time.Sleep(50 * time.Millisecond)
+	atomic.StoreInt32(&w.done, 1)
+	return nil
+}
+
+func TestConfigure(t *testing.T) {
+	log.Reset()
+
+	old := &slowWriter{started: make(chan struct{})}
+	log.DefaultLogWriter(old)
+	log.Module("asteria.user").LogLevel(level.Error)
+	log.Module("asteria.order").LogLevel(level.Error)
+
+	go log.Module("asteria.order").Error("written to the old writer")
+	<-old.started
+
+	current := &MockWriter{}
+	fileLine := true
+	log.Configure(log.ModuleSettings{Level: level.Info, Writer: current}, map[string]log.ModuleSettings{
+		"asteria.user":     {Level: level.Debug},
+		"asteria.user.job": {FileLine: &fileLine},
+	})
+
+	// the write in flight using the old writer finished before Configure returned
+	assert.Equal(t, int32(1), atomic.LoadInt32(&old.done))
+
+	assert.Equal(t, level.Info, log.GetDefaultConfig().LogLevel)
+	assert.True(t, log.Module("asteria.user").DebugEnabled())
+	assert.True(t, log.Module("asteria.user.job").DebugEnabled())
+	assert.False(t, log.Module("asteria.order").DebugEnabled())
+	assert.True(t, log.Module("asteria.order").InfoEnabled())
+	assert.Equal(t, current, log.Module("asteria.user").GetWriter())
+}
@@ -17,8 +17,9 @@ type M map[string]interface{}
 
 // ReOpenAll reopen all logger
 func ReOpenAll() map[string]error {
-	errors := make(map[string]error, len(loggers))
-	for name, l := range loggers {
+	all := All()
+	errors := make(map[string]error, len(all))
+	for name, l := range all {
 		errors[name] = l.ReOpen()
 	}
 
@@ -32,8 +33,9 @@ func ReOpen() error {
 
 // CloseAll close all loggers
 func CloseAll() map[string]error {
-	errors := make(map[string]error, len(loggers))
-	for name, l := range loggers {
+	all := All()
+	errors := make(map[string]error, len(all))
+	for name, l := range all {
 		errors[name] = l.Close()
 	}
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mylxsw/asteria/filter"
@@ -21,9 +22,17 @@ var moduleLock sync.RWMutex
 // Loggers is a map holds all loggers
 type Loggers map[string]*AsteriaLogger
 
-// All return all loggers
+// All return all loggers, the result is a snapshot which is safe to iterate
 func All() Loggers {
-	return loggers
+	moduleLock.RLock()
+	defer moduleLock.RUnlock()
+
+	res := make(Loggers, len(loggers))
+	for name, l := range loggers {
+		res[name] = l
+	}
+
+	return res
 }
 
 // DynamicModuleName set whether enable dynamic module name generate
@@ -32,7 +41,9 @@ func (loggers Loggers) DynamicModuleName(enable bool) {
 	defer moduleLock.Unlock()
 
 	for _, l := range loggers {
+		l.lock.Lock()
 		l.dynamicModuleName = enable
+		l.lock.Unlock()
 	}
 
 	defaultLogConfig.DynamicModuleName = enable
@@ -44,7 +55,9 @@ func (loggers Loggers) WithFileLine(enable bool) {
 	defer moduleLock.Unlock()
 
 	for _, l := range loggers {
+		l.lock.Lock()
 		l.fileLine = enable
+		l.lock.Unlock()
 	}
 
 	defaultLogConfig.WithFileLine = enable
@@ -56,51 +69,141 @@ func (loggers Loggers) Location(loc *time.Location) {
 	defer moduleLock.Unlock()
 
 	for _, l := range loggers {
+		l.lock.Lock()
 		l.timeLocation = loc
+		l.lock.Unlock()
 	}
 
 	defaultLogConfig.TimeLocation = loc
 }
 
-// LogLevel 设置全局默认日志输出级别
+// LogLevel 设置全局默认日志输出级别, the levels set for modules and rules will be cleared
 func (loggers Loggers) LogLevel(le level.Level) {
 	moduleLock.Lock()
 	defer moduleLock.Unlock()
 
 	for _, l := range loggers {
-		l.level = le
+		l.own.level = 0
+	}
+	for _, r := range defaultLogConfig.Rules {
+		r.own.level = 0
 	}
 
 	defaultLogConfig.LogLevel = le
+	loggers.resolve()
 }
 
-// LogFormatter 设置全局默认的日志输出格式化器
+// LogFormatter 设置全局默认的日志输出格式化器, the formatters set for modules and rules will be cleared
 func (loggers Loggers) LogFormatter(f formatter.Formatter) {
 	moduleLock.Lock()
 	defer moduleLock.Unlock()
 
 	for _, l := range loggers {
-		l.formatter = f
+		l.own.formatter = nil
+	}
+	for _, r := range defaultLogConfig.Rules {
+		r.own.formatter = nil
 	}
 
 	defaultLogConfig.LogFormatter = f
+	loggers.resolve()
 }
 
-// LogWriter 设置全局默认的日志输出器
+// LogWriter 设置全局默认的日志输出器, the writers set for modules and rules will be cleared
 func (loggers Loggers) LogWriter(w writer.Writer) {
 	moduleLock.Lock()
 	defer moduleLock.Unlock()
 
 	for _, l := range loggers {
-		l.writer = w
+		l.own.writer = nil
+	}
+	for _, r := range defaultLogConfig.Rules {
+		r.own.writer = nil
 	}
 
 	defaultLogConfig.LogWriter = w
+	loggers.resolve()
+}
+
+// ModuleSettings is the settings of a module for Configure, the zero values are not set
+type ModuleSettings struct {
+	Level     level.Level
+	Formatter formatter.Formatter
+	Writer    writer.Writer
+	FileLine  *bool
+}
+
+// configureLock serialize Configure, including waiting for the writes in flight
+var configureLock sync.Mutex
+
+// generation is switched by Configure, inflight count the writes in flight for each generation,
+// so that Configure can wait for the writes using the old writers
+var generation uint32
+var inflight [2]int64
+
+// Configure replace the default settings and the settings of modules at once, like calling LogLevel,
+// LogFormatter, LogWriter, WithFileLine on all loggers and setting the modules, but loggers never see
+// a part of the changes. The settings set for modules not listed are cleared, rules are kept.
+//
+// It returns after the writes in flight using the old settings finished, so that the old writers can be closed
+func Configure(defaults ModuleSettings, modules map[string]ModuleSettings) {
+	configureLock.Lock()
+	defer configureLock.Unlock()
+
+	moduleLock.Lock()
+
+	if defaults.Level != 0 {
+		defaultLogConfig.LogLevel = defaults.Level
+	}
+	if defaults.Formatter != nil {
+		defaultLogConfig.LogFormatter = defaults.Formatter
+	}
+	if defaults.Writer != nil {
+		defaultLogConfig.LogWriter = defaults.Writer
+	}
+	if defaults.FileLine != nil {
+		defaultLogConfig.WithFileLine = *defaults.FileLine
+	}
+
+	for name := range modules {
+		if _, ok := loggers[name]; !ok {
+			loggers[name] = newModule(name)
+		}
+	}
+
+	for name, l := range loggers {
+		m := modules[name]
+		l.own.level, l.own.formatter, l.own.writer = m.Level, m.Formatter, m.Writer
+
+		fileLine := defaultLogConfig.WithFileLine
+		if m.FileLine != nil {
+			fileLine = *m.FileLine
+		}
+
+		l.lock.Lock()
+		l.fileLine = fileLine
+		l.lock.Unlock()
+	}
+
+	loggers.resolve()
+
+	// the writes started before are counted to the old generation
+	old := atomic.AddUint32(&generation, 1) - 1
+	moduleLock.Unlock()
+
+	for atomic.LoadInt64(&inflight[old%2]) > 0 {
+		time.Sleep(time.Millisecond)
+	}
 }
 
 // AsteriaLogger 日志对象
+//
+// Level, formatter, writer and filters are inherited through dotted module names,
+// the settings of asteria.user apply to asteria.user.jobs unless it is overridden
 type AsteriaLogger struct {
-	moduleName        string
+	moduleName string
+	// own is the settings set for this logger, others are the effective settings
+	own               settings
 	level             level.Level
 	formatter         formatter.Formatter
 	writer            writer.Writer
@@ -109,51 +212,53 @@ type AsteriaLogger struct {
 	fileLine          bool
 	globalContext     func(c event.Fields)
 	filters           []filter.Chain
+	errorHandler      ErrorHandler
 
 	lock sync.RWMutex
 }
 
 func (module *AsteriaLogger) DebugEnabled() bool {
-	return level.Debug <= module.level
+	return level.Debug <= module.getLevel()
 }
 
 func (module *AsteriaLogger) InfoEnabled() bool {
-	return level.Info <= module.level
+	return level.Info <= module.getLevel()
 }
 
 func (module *AsteriaLogger) NoticeEnabled() bool {
-	return level.Notice <= module.level
+	return level.Notice <= module.getLevel()
 }
 
 func (module *AsteriaLogger) WarningEnabled() bool {
-	return level.Warning <= module.level
+	return level.Warning <= module.getLevel()
 }
 
 func (module *AsteriaLogger) ErrorEnabled() bool {
-	return level.Error <= module.level
+	return level.Error <= module.getLevel()
 }
 
 func (module *AsteriaLogger) CriticalEnabled() bool {
-	return level.Critical <= module.level
+	return level.Critical <= module.getLevel()
 }
 
 func (module *AsteriaLogger) AlertEnabled() bool {
-	return level.Alert <= module.level
+	return level.Alert <= module.getLevel()
 }
 
 func (module *AsteriaLogger) EmergencyEnabled() bool {
-	return level.Emergency <= module.level
+	return level.Emergency <= module.getLevel()
 }
 
-// AddFilter append a filter to logger
+// AddFilter append a filter to logger, the filter applies to descendants too
 func (module *AsteriaLogger) AddFilter(f ...filter.Chain) {
-	module.lock.Lock()
-	defer module.lock.Unlock()
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
 
-	module.filters = append(module.filters, f...)
+	module.own.filters = append(module.own.filters, f...)
+	loggers.resolveDescendants(module.moduleName)
 }
 
-// Filters return all filters
+// Filters return all filters, including the filters inherited from parents
 func (module *AsteriaLogger) Filters() []filter.Chain {
 	module.lock.RLock()
 	defer module.lock.RUnlock()
@@ -187,6 +292,9 @@ type DefaultConfig struct {
 	DynamicModuleName bool
 	GlobalFields      func(c event.Fields)
 	GlobalFilters     []filter.Chain
+	ContextExtractors []ContextExtractor
+	Rules             []*ModuleRule
+	ErrorHandler      ErrorHandler
 }
 
 // 默认配置信息
@@ -205,6 +313,9 @@ func Reset() {
 		WithFileLine:      false,
 		DynamicModuleName: false,
 		GlobalFilters:     make([]filter.Chain, 0),
+		ContextExtractors: make([]ContextExtractor, 0),
+		Rules:             make([]*ModuleRule, 0),
+		ErrorHandler:      PanicErrorHandler,
 	}
 
 	loggers = make(Loggers)
@@ -239,28 +350,31 @@ func DefaultDynamicModuleName(enable bool) {
 	defaultLogConfig.DynamicModuleName = enable
 }
 
-// DefaultLogLevel 设置全局默认日志输出级别
+// DefaultLogLevel 设置全局默认日志输出级别, existing modules without their own level (or from a rule or parent) use it too
 func DefaultLogLevel(l level.Level) {
 	moduleLock.Lock()
 	defer moduleLock.Unlock()
 
 	defaultLogConfig.LogLevel = l
+	loggers.resolve()
 }
 
-// DefaultLogFormatter 设置全局默认的日志输出格式化器
+// DefaultLogFormatter 设置全局默认的日志输出格式化器, existing modules without their own formatter (or from a rule or parent) use it too
 func DefaultLogFormatter(f formatter.Formatter) {
 	moduleLock.Lock()
 	defer moduleLock.Unlock()
 
 	defaultLogConfig.LogFormatter = f
+	loggers.resolve()
 }
 
-// DefaultLogWriter 设置全局默认的日志输出器
+// DefaultLogWriter 设置全局默认的日志输出器, existing modules without their own writer (or from a rule or parent) use it too
 func DefaultLogWriter(w writer.Writer) {
 	moduleLock.Lock()
 	defer moduleLock.Unlock()
 
 	defaultLogConfig.LogWriter = w
+	loggers.resolve()
 }
 
 // GlobalFields set global fields
@@ -280,20 +394,22 @@ func Module(moduleName string) *AsteriaLogger {
 		return logger
 	}
 
-	logger := &AsteriaLogger{
+	logger := newModule(moduleName)
+	loggers[moduleName] = logger
+	logger.resolve()
+
+	return logger
+}
+
+// newModule create a logger with the default config, moduleLock must be held
+func newModule(moduleName string) *AsteriaLogger {
+	return &AsteriaLogger{
 		moduleName:        moduleName,
-		formatter:         defaultLogConfig.LogFormatter,
-		writer:            defaultLogConfig.LogWriter,
-		level:             defaultLogConfig.LogLevel,
 		timeLocation:      defaultLogConfig.TimeLocation,
 		dynamicModuleName: defaultLogConfig.DynamicModuleName,
 		fileLine:          defaultLogConfig.WithFileLine,
 		globalContext:     defaultLogConfig.GlobalFields,
 	}
-
-	loggers[moduleName] = logger
-
-	return logger
 }
 
 // Location set time location for module
@@ -335,88 +451,166 @@ func (module *AsteriaLogger) GlobalFields(f func(c event.Fields)) *AsteriaLogger
 }
 
 func (module *AsteriaLogger) Output(callDepth int, le level.Level, userContext Fields, v ...interface{}) {
-	if le > module.level {
-		return
+	module.output(callDepth+1, le, userContext, nil, v...)
+}
+
+func (module *AsteriaLogger) output(callDepth int, le level.Level, userContext Fields, typed []Field, v ...interface{}) {
+	module.lock.RLock()
+	logLevel, fileLine, dynamicModuleName := module.level, module.fileLine, module.dynamicModuleName
+	timeLocation, globalContext := module.timeLocation, module.globalContext
+	logFormatter, logWriter, moduleFilters := module.formatter, module.writer, module.filters
+
+	// count the write in flight, it must be done with reading the writer, see Configure
+	var counter *int64
+	if le <= logLevel {
+		counter = &inflight[atomic.LoadUint32(&generation)%2]
+		atomic.AddInt64(counter, 1)
 	}
+	module.lock.RUnlock()
 
-	if userContext == nil {
-		userContext = Fields{}
+	if counter == nil {
+		return
 	}
+	defer atomic.AddInt64(counter, -1)
 
-	logCtx := event.Fields{
-		CustomFields: userContext,
-		GlobalFields: Fields{},
+	globalFilters := GlobalFilters()
+
+	// the maps are allocated only if they are written, filters and the global fields callback may add fields
+	logCtx := event.Fields{CustomFields: userContext}
+	if globalContext != nil || len(globalFilters) > 0 || len(moduleFilters) > 0 {
+		if logCtx.CustomFields == nil {
+			logCtx.CustomFields = Fields{}
+		}
+		logCtx.GlobalFields = Fields{}
 	}
 
 	moduleName := module.moduleName
-	if module.dynamicModuleName || module.fileLine || !level.In(le, []level.Level{level.Debug, level.Info, level.Notice, level.Warning}) {
+	if dynamicModuleName || fileLine || !level.In(le, []level.Level{level.Debug, level.Info, level.Notice, level.Warning}) {
 		cg := misc.CallGraph(callDepth)
-		if module.fileLine || !level.In(le, []level.Level{level.Debug, level.Info, level.Notice, level.Warning}) {
+		if fileLine || !level.In(le, []level.Level{level.Debug, level.Info, level.Notice, level.Warning}) {
+			if logCtx.GlobalFields == nil {
+				logCtx.GlobalFields = make(Fields, 3)
+			}
 			logCtx.GlobalFields["file"] = cg.FileName
 			logCtx.GlobalFields["line"] = cg.Line
 			logCtx.GlobalFields["package"] = cg.PackageName
 		}
 
-		if module.dynamicModuleName {
+		if dynamicModuleName {
 			moduleName = strings.Replace(cg.PackageName, "/", ".", -1)
 		}
 	}
 
-	if module.globalContext != nil {
-		cf := module.globalContext
-		if cf != nil {
-			cf(logCtx)
-		}
+	if globalContext != nil {
+		globalContext(logCtx)
 	}
 
 	f := event.Event{
-		Time:     time.Now().In(module.timeLocation),
-		Module:   moduleName,
-		Level:    le,
-		Fields:   logCtx,
-		Messages: v,
+		Time:        time.Now().In(timeLocation),
+		Module:      moduleName,
+		Level:       le,
+		Fields:      logCtx,
+		Messages:    v,
+		TypedFields: typed,
 	}
 
 	var chain filter.Filter = func(f event.Event) {
-		message := module.getFormatter().Format(f)
-		if err := module.getWriter().Write(le, f.Module, message); err != nil {
-			panic(fmt.Sprintf("can not write to output: %s", err))
+		buf := misc.GetBuffer()
+		defer misc.PutBuffer(buf)
+
+		*buf = formatter.AppendFormat(logFormatter, *buf, f)
+
+		if err := write(logWriter, f, *buf); err != nil {
+			module.getErrorHandler()(f, logWriter, string(*buf), err)
 		}
 	}
 
-	filters := append(GlobalFilters(), module.Filters()...)
-	for i := range filters {
-		ff := filters[len(filters)-i-1]
-		chain = ff(chain)
+	// global filters run before the filters of module
+	for i := len(moduleFilters) - 1; i >= 0; i-- {
+		chain = moduleFilters[i](chain)
+	}
+	for i := len(globalFilters) - 1; i >= 0; i-- {
+		chain = globalFilters[i](chain)
 	}
 
 	chain(f)
 }
 
+// write the event to w, WriteEvent is used if w is an EventWriter, and WriteBytes if w is a BytesWriter
+func write(w writer.Writer, evt event.Event, message []byte) error {
+	if ew, ok := w.(writer.EventWriter); ok {
+		return ew.WriteEvent(evt, message)
+	}
+
+	if bw, ok := w.(writer.BytesWriter); ok {
+		return bw.WriteBytes(evt.Level, evt.Module, message)
+	}
+
+	return w.Write(evt.Level, evt.Module, string(message))
+}
+
 // Default 获取默认的模块日志
 func Default() *AsteriaLogger {
 	return Module("main")
 }
 
-// LogLevel 设置日志输出级别
+// LogLevel 设置日志输出级别, descendants inherit it unless they override it
 func (module *AsteriaLogger) LogLevel(le level.Level) *AsteriaLogger {
-	module.lock.Lock()
-	defer module.lock.Unlock()
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
 
-	module.level = le
+	module.own.level = le
+	loggers.resolveDescendants(module.moduleName)
 
 	return module
 }
 
-// Formatter 设置日志格式化器
+// Formatter 设置日志格式化器, descendants inherit it unless they override it
 func (module *AsteriaLogger) Formatter(f formatter.Formatter) *AsteriaLogger {
-	module.lock.Lock()
-	defer module.lock.Unlock()
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	module.own.formatter = f
+	loggers.resolveDescendants(module.moduleName)
 
-	module.formatter = f
 	return module
 }
 
+// GetModuleName return the module name
+func (module *AsteriaLogger) GetModuleName() string {
+	return module.moduleName
+}
+
+// GetLevel return the effective level of the module
+func (module *AsteriaLogger) GetLevel() level.Level {
+	return module.getLevel()
+}
+
+// GetOwnLevel return the level set for the module, false if the level is inherited from rules, parents or default
+func (module *AsteriaLogger) GetOwnLevel() (level.Level, bool) {
+	moduleLock.RLock()
+	defer moduleLock.RUnlock()
+
+	return module.own.level, module.own.level != 0
+}
+
+// GetFormatter return the effective formatter of the module
+func (module *AsteriaLogger) GetFormatter() formatter.Formatter {
+	return module.getFormatter()
+}
+
+// GetWriter return the effective writer of the module
+func (module *AsteriaLogger) GetWriter() writer.Writer {
+	return module.getWriter()
+}
+
+func (module *AsteriaLogger) getLevel() level.Level {
+	module.lock.RLock()
+	defer module.lock.RUnlock()
+
+	return module.level
+}
+
 func (module *AsteriaLogger) getFormatter() formatter.Formatter {
 	module.lock.RLock()
 	defer module.lock.RUnlock()
@@ -424,12 +618,14 @@ func (module *AsteriaLogger) getFormatter() formatter.Formatter {
 	return module.formatter
 }
 
-// Writer 设置日志输出器
+// Writer 设置日志输出器, descendants inherit it unless they override it
 func (module *AsteriaLogger) Writer(w writer.Writer) *AsteriaLogger {
-	module.lock.Lock()
-	defer module.lock.Unlock()
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	module.own.writer = w
+	loggers.resolveDescendants(module.moduleName)
 
-	module.writer = w
 	return module
 }
 
@@ -475,7 +671,23 @@ func (module *AsteriaLogger) WithFields(c Fields) Logger {
 	}
 }
 
+// Typed 带有类型化字段的日志输出, the fields are written without building a map
+func (module *AsteriaLogger) Typed(fields ...Field) Logger {
+	return &ContextLogger{
+		logger: module,
+		typed:  fields,
+	}
+}
+
+// With 添加 @ 字段, data of type Field or []Field is added as typed fields like Typed
 func (module *AsteriaLogger) With(data interface{}) Logger {
+	switch fields := data.(type) {
+	case Field:
+		return module.Typed(fields)
+	case []Field:
+		return module.Typed(fields...)
+	}
+
 	return module.WithFields(Fields{
 		"@": data,
 	})
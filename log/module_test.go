@@ -11,6 +11,7 @@ import (
 	"github.com/mylxsw/asteria/formatter"
 	"github.com/mylxsw/asteria/level"
 	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/asteria/writer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -469,3 +470,44 @@ func TestLoggers_LogLevel(t *testing.T) {
 		assert.Equal(t, cas[7] == 1, log.EmergencyEnabled(), "current=%s, target=EMERGENCY, enabled=%v", le.GetLevelName(), log.EmergencyEnabled())
 	}
 }
+
+func TestFormattedStackWriter(t *testing.T) {
+	log.Reset()
+
+	console := &MockWriter{}
+	file := &MockWriter{}
+
+	stack := writer.NewStackWriter()
+	stack.PushWithFormatter(console, formatter.NewDefaultFormatter(false))
+	stack.PushWithFormatter(file, formatter.NewJSONFormatter())
+
+	log.Module("test").Writer(stack).WithFields(log.Fields{"user_id": 123}).Info("Hello")
+
+	assert.Regexp(t, regexp.MustCompile(`INFO test Hello {"user_id":123}`), console.LastMessage)
+	assert.Equal(t, "Hello", jsoniter.Get([]byte(file.LastMessage), "message").ToString())
+}
+
+type BytesWriter struct {
+	Messages []string
+	MockWriter
+}
+
+func (w *BytesWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	// the message is only valid during the call
+	w.Messages = append(w.Messages, string(message))
+	return nil
+}
+
+func TestBytesWriter(t *testing.T) {
+	log.Reset()
+
+	bw := &BytesWriter{}
+	logger := log.Module("test").Formatter(formatter.NewJSONFormatter()).Writer(bw)
+	logger.Info("Hello")
+	logger.Typed(log.Int("user_id", 123)).Info("World")
+
+	assert.Equal(t, 0, bw.WriteCount)
+	assert.Len(t, bw.Messages, 2)
+	assert.Equal(t, "Hello", jsoniter.Get([]byte(bw.Messages[0]), "message").ToString())
+	assert.Equal(t, 123, jsoniter.Get([]byte(bw.Messages[1]), "context", "user_id").ToInt())
+}
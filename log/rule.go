@@ -0,0 +1,144 @@
+package log
+
+import (
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+)
+
+// ModuleRule apply level, formatter and writer to all modules matched
+//
+// The settings set for a module take precedence over rules, and rules take precedence over the settings
+// inherited from parents. Rules with higher priority are evaluated first, for the rules with same priority,
+// the one added later is evaluated first
+type ModuleRule struct {
+	pattern  string
+	match    func(moduleName string) bool
+	priority int
+	seq      int
+	own      settings
+}
+
+// ruleSeq is the sequence of rules added, moduleLock must be held when changing it
+var ruleSeq int
+
+// Rule add a rule for modules matching the glob pattern, such as billing.*
+//
+// The pattern syntax is the same as path.Match, invalid pattern matches nothing
+func Rule(pattern string) *ModuleRule {
+	return addRule(pattern, func(moduleName string) bool {
+		matched, _ := path.Match(pattern, moduleName)
+		return matched
+	})
+}
+
+// RegexRule add a rule for modules matching the regular expression
+func RegexRule(re *regexp.Regexp) *ModuleRule {
+	return addRule(re.String(), re.MatchString)
+}
+
+func addRule(pattern string, match func(moduleName string) bool) *ModuleRule {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	ruleSeq++
+	rule := &ModuleRule{pattern: pattern, match: match, seq: ruleSeq}
+	defaultLogConfig.Rules = append(defaultLogConfig.Rules, rule)
+	sortRules(defaultLogConfig.Rules)
+
+	return rule
+}
+
+// Rules return all rules in the order they are evaluated
+func Rules() []*ModuleRule {
+	moduleLock.RLock()
+	defer moduleLock.RUnlock()
+
+	return append([]*ModuleRule{}, defaultLogConfig.Rules...)
+}
+
+// Pattern return the pattern of the rule
+func (rule *ModuleRule) Pattern() string {
+	return rule.pattern
+}
+
+// Match return whether the module matches the rule
+func (rule *ModuleRule) Match(moduleName string) bool {
+	return rule.match(moduleName)
+}
+
+// Priority set the priority of the rule, default is 0
+func (rule *ModuleRule) Priority(priority int) *ModuleRule {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	rule.priority = priority
+	sortRules(defaultLogConfig.Rules)
+	loggers.resolve()
+
+	return rule
+}
+
+// LogLevel set the level for modules matched
+func (rule *ModuleRule) LogLevel(le level.Level) *ModuleRule {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	rule.own.level = le
+	loggers.resolve()
+
+	return rule
+}
+
+// Formatter set the formatter for modules matched
+func (rule *ModuleRule) Formatter(f formatter.Formatter) *ModuleRule {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	rule.own.formatter = f
+	loggers.resolve()
+
+	return rule
+}
+
+// Writer set the writer for modules matched
+func (rule *ModuleRule) Writer(w writer.Writer) *ModuleRule {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	rule.own.writer = w
+	loggers.resolve()
+
+	return rule
+}
+
+// Remove the rule, modules matched will fallback to other rules or their parents
+func (rule *ModuleRule) Remove() {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	rules := make([]*ModuleRule, 0, len(defaultLogConfig.Rules))
+	for _, r := range defaultLogConfig.Rules {
+		if r != rule {
+			rules = append(rules, r)
+		}
+	}
+
+	defaultLogConfig.Rules = rules
+	loggers.resolve()
+}
+
+// sortRules sort the rules by priority desc, then by the order added desc
+func sortRules(rules []*ModuleRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].priority != rules[j].priority {
+			return rules[i].priority > rules[j].priority
+		}
+
+		return rules[i].seq > rules[j].seq
+	})
+}
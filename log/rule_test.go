@@ -0,0 +1,73 @@
+package log_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRule(t *testing.T) {
+	log.Reset()
+
+	defaultWriter := &MockWriter{}
+	log.DefaultLogWriter(defaultWriter)
+	log.DefaultLogFormatter(formatter.NewJSONFormatter())
+	log.DefaultLogLevel(level.Info)
+
+	invoice := log.Module("billing.invoice")
+	assert.False(t, invoice.DebugEnabled())
+
+	billingWriter := &MockWriter{}
+	rule := log.Rule("billing.*").LogLevel(level.Debug).Writer(billingWriter)
+
+	// applied to existing and new loggers
+	assert.True(t, invoice.DebugEnabled())
+	assert.True(t, log.Module("billing.payment.refund").DebugEnabled())
+	assert.False(t, log.Module("billing").DebugEnabled())
+	assert.False(t, log.Module("user").DebugEnabled())
+
+	invoice.Debug("hello")
+	assert.Equal(t, 1, billingWriter.WriteCount)
+	assert.Equal(t, 0, defaultWriter.WriteCount)
+
+	// the settings of module take precedence over rules
+	invoice.LogLevel(level.Error)
+	assert.False(t, invoice.InfoEnabled())
+	invoice.ClearLogLevel()
+	assert.True(t, invoice.DebugEnabled())
+
+	// rules take precedence over parents
+	log.Module("billing").LogLevel(level.Error)
+	assert.True(t, invoice.DebugEnabled())
+
+	rule.Remove()
+	assert.False(t, invoice.InfoEnabled())
+	invoice.Error("hello")
+	assert.Equal(t, 1, billingWriter.WriteCount)
+	assert.Equal(t, 1, defaultWriter.WriteCount)
+}
+
+func TestRule_Priority(t *testing.T) {
+	log.Reset()
+	log.DefaultLogLevel(level.Info)
+
+	low := log.Rule("billing.*").LogLevel(level.Debug)
+	log.RegexRule(regexp.MustCompile(`^billing\.(invoice|payment)$`)).LogLevel(level.Warning)
+
+	// rule added later is evaluated first
+	assert.False(t, log.Module("billing.invoice").InfoEnabled())
+	assert.True(t, log.Module("billing.refund").DebugEnabled())
+
+	low.Priority(10)
+	assert.True(t, log.Module("billing.invoice").DebugEnabled())
+	assert.Equal(t, "billing.*", log.Rules()[0].Pattern())
+
+	// Loggers.LogLevel clear the levels of rules
+	log.All().LogLevel(level.Error)
+	assert.False(t, log.Module("billing.invoice").WarningEnabled())
+	assert.False(t, log.Module("billing.refund").WarningEnabled())
+}
@@ -0,0 +1,171 @@
+package log
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+)
+
+// SignalOptions is the options for HandleSignals
+type SignalOptions struct {
+	// DisableReOpen disable ReOpenAll on SIGHUP
+	DisableReOpen bool
+	// DisableLevelToggle disable switching levels on SIGUSR1 (to Debug) and SIGUSR2 (back to configured levels)
+	DisableLevelToggle bool
+	// Writer is the fallback writer for the results, default is stderr
+	Writer writer.Writer
+}
+
+// HandleSignals handle signals for loggers until ctx is done
+//
+//	SIGHUP  reopen all loggers, such as reopen log files after rotated by logrotate
+//	SIGUSR1 switch all loggers to Debug
+//	SIGUSR2 switch all loggers back to their configured levels
+//
+// The results are written to the fallback writer, so that the errors of loggers can be seen.
+// Signals are not supported on windows, HandleSignals does nothing there
+func HandleSignals(ctx context.Context, opts SignalOptions) {
+	var sigs []os.Signal
+	if !opts.DisableReOpen {
+		sigs = append(sigs, reOpenSignals...)
+	}
+	if !opts.DisableLevelToggle {
+		sigs = append(sigs, debugSignals...)
+		sigs = append(sigs, restoreSignals...)
+	}
+
+	if len(sigs) == 0 {
+		return
+	}
+
+	if opts.Writer == nil {
+		opts.Writer = writer.NewStreamWriter(os.Stderr)
+	}
+
+	fallback := &AsteriaLogger{
+		moduleName:   "asteria.signal",
+		level:        level.Debug,
+		formatter:    formatter.NewDefaultFormatter(false),
+		writer:       opts.Writer,
+		timeLocation: time.Local,
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		var snapshot *levelSnapshot
+		for {
+			select {
+			case sig := <-ch:
+				switch {
+				case signalIn(sig, reOpenSignals):
+					reOpenWithResults(fallback)
+				case signalIn(sig, debugSignals):
+					if snapshot == nil {
+						snapshot = snapshotLevels()
+					}
+					All().LogLevel(level.Debug)
+					fallback.Noticef("all loggers are switched to debug by signal %s", sig)
+				case signalIn(sig, restoreSignals):
+					if snapshot != nil {
+						snapshot.restore()
+						snapshot = nil
+					}
+					fallback.Noticef("all loggers are switched back to configured levels by signal %s", sig)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reOpenWithResults reopen all loggers, and write the results to fallback logger
+func reOpenWithResults(fallback *AsteriaLogger) {
+	results := ReOpenAll()
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := 0
+	for _, name := range names {
+		if err := results[name]; err != nil {
+			failed++
+			fallback.WithFields(Fields{"module": name}).Errorf("reopen failed: %v", err)
+		}
+	}
+
+	fallback.WithFields(Fields{"total": len(results), "failed": failed}).Notice("all loggers are reopened")
+}
+
+func signalIn(sig os.Signal, sigs []os.Signal) bool {
+	for _, s := range sigs {
+		if s == sig {
+			return true
+		}
+	}
+
+	return false
+}
+
+// levelSnapshot is the levels of default config, modules and rules
+type levelSnapshot struct {
+	defaultLevel level.Level
+	modules      map[*AsteriaLogger]level.Level
+	rules        map[*ModuleRule]level.Level
+}
+
+// snapshotLevels take a snapshot of the levels configured
+func snapshotLevels() *levelSnapshot {
+	moduleLock.RLock()
+	defer moduleLock.RUnlock()
+
+	snapshot := &levelSnapshot{
+		defaultLevel: defaultLogConfig.LogLevel,
+		modules:      make(map[*AsteriaLogger]level.Level),
+		rules:        make(map[*ModuleRule]level.Level),
+	}
+
+	for _, l := range loggers {
+		if l.own.level != 0 {
+			snapshot.modules[l] = l.own.level
+		}
+	}
+
+	for _, r := range defaultLogConfig.Rules {
+		if r.own.level != 0 {
+			snapshot.rules[r] = r.own.level
+		}
+	}
+
+	return snapshot
+}
+
+// restore the levels from snapshot
+func (snapshot *levelSnapshot) restore() {
+	moduleLock.Lock()
+	defer moduleLock.Unlock()
+
+	defaultLogConfig.LogLevel = snapshot.defaultLevel
+	for _, l := range loggers {
+		l.own.level = snapshot.modules[l]
+	}
+
+	for _, r := range defaultLogConfig.Rules {
+		r.own.level = snapshot.rules[r]
+	}
+
+	loggers.resolve()
+}
@@ -0,0 +1,88 @@
+//go:build !windows
+// +build !windows
+
+package log_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type SyncWriter struct {
+	messages  []string
+	reOpenErr error
+
+	lock sync.Mutex
+}
+
+func (w *SyncWriter) Write(le level.Level, module string, message string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.messages = append(w.messages, message)
+	return nil
+}
+
+func (w *SyncWriter) ReOpen() error {
+	return w.reOpenErr
+}
+
+func (w *SyncWriter) Close() error {
+	return nil
+}
+
+func (w *SyncWriter) Messages() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return strings.Join(w.messages, "\n")
+}
+
+func waitFor(cond func() bool) {
+	for i := 0; i < 100 && !cond(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandleSignals(t *testing.T) {
+	log.Reset()
+	defer log.Reset()
+
+	log.DefaultLogWriter(&SyncWriter{})
+	log.DefaultLogLevel(level.Info)
+	log.Module("asteria.user").LogLevel(level.Error)
+	log.Module("asteria.order").Writer(&SyncWriter{reOpenErr: errors.New("permission denied")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fallback := &SyncWriter{}
+	log.HandleSignals(ctx, log.SignalOptions{Writer: fallback})
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	waitFor(func() bool { return strings.Contains(fallback.Messages(), "all loggers are reopened") })
+	assert.Contains(t, fallback.Messages(), "reopen failed: permission denied")
+	assert.Contains(t, fallback.Messages(), `"module":"asteria.order"`)
+	assert.Contains(t, fallback.Messages(), `"failed":1`)
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	waitFor(func() bool { return log.Module("asteria.user").DebugEnabled() })
+	assert.True(t, log.Module("asteria.user").DebugEnabled())
+	assert.True(t, log.Module("asteria.order").DebugEnabled())
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	waitFor(func() bool { return !log.Module("asteria.user").DebugEnabled() })
+	assert.False(t, log.Module("asteria.user").WarningEnabled())
+	assert.True(t, log.Module("asteria.order").InfoEnabled())
+	assert.False(t, log.Module("asteria.order").DebugEnabled())
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	reOpenSignals  = []os.Signal{syscall.SIGHUP}
+	debugSignals   = []os.Signal{syscall.SIGUSR1}
+	restoreSignals = []os.Signal{syscall.SIGUSR2}
+)
@@ -0,0 +1,10 @@
+package log
+
+import "os"
+
+// signals for loggers are not supported on windows
+var (
+	reOpenSignals  []os.Signal
+	debugSignals   []os.Signal
+	restoreSignals []os.Signal
+)
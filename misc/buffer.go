@@ -0,0 +1,33 @@
+package misc
+
+import (
+	"sync"
+)
+
+// maxPooledBufferSize is the max capacity of buffers put back to pool, larger ones are dropped
+// to avoid holding too much memory after logging a huge message
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+// GetBuffer get an empty buffer from pool, put it back by PutBuffer after use
+func GetBuffer() *[]byte {
+	buf := bufferPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+
+	return buf
+}
+
+// PutBuffer put the buffer back to pool, the buffer must not be used after that
+func PutBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBufferSize {
+		return
+	}
+
+	bufferPool.Put(buf)
+}
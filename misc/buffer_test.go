@@ -0,0 +1,22 @@
+package misc_test
+
+import (
+	"testing"
+
+	"github.com/mylxsw/asteria/misc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	buf := misc.GetBuffer()
+	assert.Empty(t, *buf)
+
+	*buf = append(*buf, "Hello, world"...)
+	misc.PutBuffer(buf)
+
+	assert.Empty(t, *misc.GetBuffer())
+
+	// large buffers are dropped
+	large := make([]byte, 0, 1<<20)
+	misc.PutBuffer(&large)
+}
@@ -22,16 +22,19 @@ var levelColorRef = map[level.Level][]color.Color{
 var levelColorRefLock sync.RWMutex
 
 func ColorfulLevelName(le level.Level) string {
+	return LevelColorWrap(le, fmt.Sprintf("[%s]", le.GetLevelNameAbbreviation()))
+}
+
+// LevelColorWrap wrap the text with the color of level
+func LevelColorWrap(le level.Level, text string) string {
 	levelColorRefLock.RLock()
 	defer levelColorRefLock.RUnlock()
 
-	levelName := fmt.Sprintf("[%s]", le.GetLevelNameAbbreviation())
-
 	if lc, ok := levelColorRef[le]; ok {
-		return color.BackgroundFunc(lc[0], lc[1])(levelName)
+		return color.BackgroundWrap(lc[0], lc[1], text)
 	}
 
-	return levelName
+	return text
 }
 
 // SetLevelWithColor specify the color for level
@@ -0,0 +1,168 @@
+package misc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Strftime format the time using strftime-style pattern, such as app-%Y%m%d%H.log
+//
+// Supported directives: %Y %y %m %d %H %M %S %j %z %%, unknown directives will be kept as is
+func Strftime(pattern string, t time.Time) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 >= len(pattern) {
+			sb.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+		switch pattern[i] {
+		case 'Y':
+			sb.WriteString(fmt.Sprintf("%04d", t.Year()))
+		case 'y':
+			sb.WriteString(fmt.Sprintf("%02d", t.Year()%100))
+		case 'm':
+			sb.WriteString(fmt.Sprintf("%02d", int(t.Month())))
+		case 'd':
+			sb.WriteString(fmt.Sprintf("%02d", t.Day()))
+		case 'H':
+			sb.WriteString(fmt.Sprintf("%02d", t.Hour()))
+		case 'M':
+			sb.WriteString(fmt.Sprintf("%02d", t.Minute()))
+		case 'S':
+			sb.WriteString(fmt.Sprintf("%02d", t.Second()))
+		case 'j':
+			sb.WriteString(fmt.Sprintf("%03d", t.YearDay()))
+		case 'z':
+			sb.WriteString(t.Format("-0700"))
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(pattern[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// strftimeDigits is the width of the directives which are formatted as digits
+var strftimeDigits = map[byte]int{'Y': 4, 'y': 2, 'm': 2, 'd': 2, 'H': 2, 'M': 2, 'S': 2, 'j': 3}
+
+// StrftimeGlob convert a strftime-style pattern to a glob pattern which matches all files generated by it,
+// the directives are converted to fixed-width digit classes, such as %Y to [0-9][0-9][0-9][0-9]
+func StrftimeGlob(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 >= len(pattern) {
+			sb.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+		if width, ok := strftimeDigits[pattern[i]]; ok {
+			sb.WriteString(strings.Repeat("[0-9]", width))
+			continue
+		}
+
+		switch pattern[i] {
+		case 'z':
+			sb.WriteString("[+-][0-9][0-9][0-9][0-9]")
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(pattern[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// StrftimeParse parse the time from value generated by Strftime with the same pattern, the fields
+// not in pattern are zero, such as the time of app-%Y%m%d.log is the start of the day.
+// loc is used unless the pattern contains %z
+func StrftimeParse(pattern string, value string, loc *time.Location) (time.Time, error) {
+	year, month, day, hour, minute, second, yearDay := 0, 1, 1, 0, 0, 0, 0
+
+	j := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '%' && i+1 < len(pattern) {
+			i++
+			c = pattern[i]
+
+			if width, ok := strftimeDigits[c]; ok {
+				if j+width > len(value) {
+					return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+				}
+
+				n, err := strconv.Atoi(value[j : j+width])
+				if err != nil || strings.IndexAny(value[j:j+width], "+-") >= 0 {
+					return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+				}
+				j += width
+
+				switch c {
+				case 'Y':
+					year = n
+				case 'y':
+					year = 2000 + n
+				case 'm':
+					month = n
+				case 'd':
+					day = n
+				case 'H':
+					hour = n
+				case 'M':
+					minute = n
+				case 'S':
+					second = n
+				case 'j':
+					yearDay = n
+				}
+				continue
+			}
+
+			if c == 'z' {
+				if j+5 > len(value) {
+					return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+				}
+
+				zone, err := time.Parse("-0700", value[j:j+5])
+				if err != nil {
+					return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+				}
+				loc = zone.Location()
+				j += 5
+				continue
+			}
+
+			if c != '%' {
+				// unknown directives are kept as is
+				if j >= len(value) || value[j] != '%' {
+					return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+				}
+				j++
+			}
+		}
+
+		if j >= len(value) || value[j] != c {
+			return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+		}
+		j++
+	}
+
+	if j != len(value) {
+		return time.Time{}, fmt.Errorf("%q does not match %q", value, pattern)
+	}
+
+	if yearDay > 0 {
+		return time.Date(year, 1, yearDay, hour, minute, second, 0, loc), nil
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, loc), nil
+}
@@ -0,0 +1,56 @@
+package misc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/misc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrftime(t *testing.T) {
+	tm := time.Date(2019, 7, 8, 9, 5, 3, 0, time.UTC)
+
+	var testCases = map[string]string{
+		"app-%Y%m%d%H.log": "app-2019070809.log",
+		"app-%y-%j.log":    "app-19-189.log",
+		"%H:%M:%S %z":      "09:05:03 +0000",
+		"100%%-%Q.log":     "100%-%Q.log",
+		"app.log":          "app.log",
+		"app-%Y%m%d.log%":  "app-20190708.log%",
+	}
+	for tc, expected := range testCases {
+		assert.Equal(t, expected, misc.Strftime(tc, tm))
+	}
+}
+
+func TestStrftimeGlob(t *testing.T) {
+	assert.Equal(t, "/var/log/app-[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9].log", misc.StrftimeGlob("/var/log/app-%Y%m%d%H.log"))
+	assert.Equal(t, "app-[0-9][0-9]-[0-9][0-9][0-9][+-][0-9][0-9][0-9][0-9].log", misc.StrftimeGlob("app-%y-%j%z.log"))
+	assert.Equal(t, "app-%-%Q.log", misc.StrftimeGlob("app-%%-%Q.log"))
+}
+
+func TestStrftimeParse(t *testing.T) {
+	var testCases = map[string]time.Time{
+		"app-%Y%m%d%H.log": time.Date(2019, 7, 8, 9, 0, 0, 0, time.UTC),
+		"app-%y-%j.log":    time.Date(2019, 7, 8, 0, 0, 0, 0, time.UTC),
+		"%H:%M:%S":         time.Date(0, 1, 1, 9, 5, 3, 0, time.UTC),
+		"100%%-%Q-%d.log":  time.Date(0, 1, 8, 0, 0, 0, 0, time.UTC),
+	}
+
+	tm := time.Date(2019, 7, 8, 9, 5, 3, 0, time.UTC)
+	for pattern, expected := range testCases {
+		res, err := misc.StrftimeParse(pattern, misc.Strftime(pattern, tm), time.UTC)
+		assert.NoError(t, err)
+		assert.True(t, expected.Equal(res), pattern)
+	}
+
+	res, err := misc.StrftimeParse("app-%Y%m%d%z.log", "app-20190708+0800.log", time.UTC)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2019, 7, 7, 16, 0, 0, 0, time.UTC).Equal(res))
+
+	for _, value := range []string{"app-error.log", "app-2019070.log", "app-201907081.log", "app-2019-708.log"} {
+		_, err := misc.StrftimeParse("app-%Y%m%d.log", value, time.UTC)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,234 @@
+//go:build go1.21
+// +build go1.21
+
+// Package slogh provides a slog.Handler backed by asteria loggers
+package slogh
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+)
+
+// callDepth is the depth from AsteriaLogger.Output to the caller of slog.Logger methods
+const callDepth = 5
+
+// Handler is a slog.Handler which route records through a log.Logger
+//
+// When the logger is an *log.AsteriaLogger, records keep the level, formatter, writer and filters of the module.
+// Attrs are written to CustomFields, and groups are written as nested fields.
+type Handler struct {
+	logger log.Logger
+	attrs  log.Fields
+	groups []string
+}
+
+// NewHandler create a new Handler
+func NewHandler(logger log.Logger) *Handler {
+	return &Handler{logger: logger, attrs: log.Fields{}}
+}
+
+// New create a *slog.Logger which write logs to logger
+func New(logger log.Logger) *slog.Logger {
+	return slog.New(NewHandler(logger))
+}
+
+// Enabled reports whether the handler handles records at the given level
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	return enabled(h.logger, ConvertLevel(l))
+}
+
+// Handle write the record to logger, fields extracted from ctx by log.ContextExtractors are added
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	fields := log.ContextFields(ctx)
+	for k, v := range cloneFields(h.attrs) {
+		fields[k] = v
+	}
+
+	if r.NumAttrs() > 0 {
+		target := groupFields(fields, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			addAttr(target, a)
+			return true
+		})
+	}
+
+	le := ConvertLevel(r.Level)
+	if logger, ok := h.logger.(*log.AsteriaLogger); ok {
+		logger.Output(callDepth, le, fields, r.Message)
+		return nil
+	}
+
+	output(h.logger.WithFields(fields), le, r.Message)
+	return nil
+}
+
+// WithAttrs return a new Handler whose attributes consists of both the receiver's attributes and the arguments
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	fields := cloneFields(h.attrs)
+	target := groupFields(fields, h.groups)
+	for _, a := range attrs {
+		addAttr(target, a)
+	}
+
+	return &Handler{logger: h.logger, attrs: fields, groups: h.groups}
+}
+
+// WithGroup return a new Handler with the given group appended to the receiver's existing groups
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	groups := make([]string, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+
+	return &Handler{logger: h.logger, attrs: h.attrs, groups: append(groups, name)}
+}
+
+// ConvertLevel convert slog level to asteria level
+func ConvertLevel(l slog.Level) level.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return level.Debug
+	case l < slog.LevelInfo+2:
+		return level.Info
+	case l < slog.LevelWarn:
+		return level.Notice
+	case l < slog.LevelError:
+		return level.Warning
+	case l < slog.LevelError+4:
+		return level.Error
+	case l < slog.LevelError+8:
+		return level.Critical
+	case l < slog.LevelError+12:
+		return level.Alert
+	}
+
+	return level.Emergency
+}
+
+// SlogLevel convert asteria level to slog level
+func SlogLevel(le level.Level) slog.Level {
+	switch le {
+	case level.Debug:
+		return slog.LevelDebug
+	case level.Info:
+		return slog.LevelInfo
+	case level.Notice:
+		return slog.LevelInfo + 2
+	case level.Warning:
+		return slog.LevelWarn
+	case level.Error:
+		return slog.LevelError
+	case level.Critical:
+		return slog.LevelError + 4
+	case level.Alert:
+		return slog.LevelError + 8
+	}
+
+	return slog.LevelError + 12
+}
+
+func enabled(logger log.Logger, le level.Level) bool {
+	switch le {
+	case level.Debug:
+		return logger.DebugEnabled()
+	case level.Info:
+		return logger.InfoEnabled()
+	case level.Notice:
+		return logger.NoticeEnabled()
+	case level.Warning:
+		return logger.WarningEnabled()
+	case level.Error:
+		return logger.ErrorEnabled()
+	case level.Critical:
+		return logger.CriticalEnabled()
+	case level.Alert:
+		return logger.AlertEnabled()
+	}
+
+	return logger.EmergencyEnabled()
+}
+
+func output(logger log.Logger, le level.Level, message string) {
+	switch le {
+	case level.Debug:
+		logger.Debug(message)
+	case level.Info:
+		logger.Info(message)
+	case level.Notice:
+		logger.Notice(message)
+	case level.Warning:
+		logger.Warning(message)
+	case level.Error:
+		logger.Error(message)
+	case level.Critical:
+		logger.Critical(message)
+	case level.Alert:
+		logger.Alert(message)
+	default:
+		logger.Emergency(message)
+	}
+}
+
+// addAttr add the attribute to fields, groups will be added as nested fields
+func addAttr(fields log.Fields, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		fields[a.Key] = a.Value.Any()
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+
+	target := fields
+	if a.Key != "" {
+		target = groupFields(fields, []string{a.Key})
+	}
+
+	for _, ga := range attrs {
+		addAttr(target, ga)
+	}
+}
+
+// groupFields return the nested fields for groups, create them if not exist
+func groupFields(fields log.Fields, groups []string) log.Fields {
+	for _, g := range groups {
+		nested, ok := fields[g].(log.Fields)
+		if !ok {
+			nested = log.Fields{}
+			fields[g] = nested
+		}
+
+		fields = nested
+	}
+
+	return fields
+}
+
+func cloneFields(fields log.Fields) log.Fields {
+	res := make(log.Fields, len(fields))
+	for k, v := range fields {
+		if nested, ok := v.(log.Fields); ok {
+			res[k] = cloneFields(nested)
+		} else {
+			res[k] = v
+		}
+	}
+
+	return res
+}
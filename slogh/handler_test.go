@@ -0,0 +1,143 @@
+//go:build go1.21
+// +build go1.21
+
+package slogh_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/asteria/slogh"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockWriter struct {
+	LastLevel   level.Level
+	LastModule  string
+	LastMessage string
+	WriteCount  int
+}
+
+func (w *MockWriter) Write(le level.Level, module string, message string) error {
+	w.LastLevel = le
+	w.LastModule = module
+	w.LastMessage = message
+	w.WriteCount++
+
+	return nil
+}
+
+func (w *MockWriter) ReOpen() error {
+	return nil
+}
+
+func (w *MockWriter) Close() error {
+	return nil
+}
+
+type jsonMessage struct {
+	Module  string                 `json:"module"`
+	Level   string                 `json:"level_name"`
+	Message string                 `json:"message"`
+	Context map[string]interface{} `json:"context"`
+}
+
+func parse(t *testing.T, message string) jsonMessage {
+	var msg jsonMessage
+	assert.NoError(t, json.Unmarshal([]byte(message), &msg))
+	return msg
+}
+
+func TestHandler(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	logger := slogh.New(log.Module("slogh").
+		Writer(mockWriter).
+		Formatter(formatter.NewJSONFormatter()).
+		LogLevel(level.Info))
+
+	logger.Debug("hello")
+	assert.Equal(t, 0, mockWriter.WriteCount)
+
+	logger.Info("hello", "user_id", 123)
+	assert.Equal(t, level.Info, mockWriter.LastLevel)
+
+	msg := parse(t, mockWriter.LastMessage)
+	assert.Equal(t, "slogh", msg.Module)
+	assert.Equal(t, "hello", msg.Message)
+	assert.Equal(t, float64(123), msg.Context["user_id"])
+
+	logger.With("request_id", "abc").
+		WithGroup("user").
+		With("id", 123).
+		Error("failed", slog.Group("role", "name", "admin"), "name", "Tom")
+
+	assert.Equal(t, level.Error, mockWriter.LastLevel)
+	msg = parse(t, mockWriter.LastMessage)
+	assert.Equal(t, "abc", msg.Context["request_id"])
+	assert.Equal(t, map[string]interface{}{
+		"id":   float64(123),
+		"name": "Tom",
+		"role": map[string]interface{}{"name": "admin"},
+	}, msg.Context["user"])
+
+	// empty group should be ignored
+	logger.WithGroup("empty").Warn("hello")
+	assert.Equal(t, level.Warning, mockWriter.LastLevel)
+	assert.NotContains(t, parse(t, mockWriter.LastMessage).Context, "empty")
+}
+
+func TestHandler_FileLine(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	logger := slogh.New(log.Module("slogh").
+		Writer(mockWriter).
+		Formatter(formatter.NewJSONFormatter()).
+		WithFileLine(true))
+
+	logger.Info("hello")
+	assert.Regexp(t, `slogh/handler_test\.go$`, parse(t, mockWriter.LastMessage).Context["#file"])
+}
+
+func TestHandler_Logger(t *testing.T) {
+	log.Reset()
+
+	mockWriter := &MockWriter{}
+	log.Module("slogh").Writer(mockWriter).Formatter(formatter.NewJSONFormatter())
+
+	logger := slogh.New(log.Module("slogh").WithFields(log.Fields{"app": "asteria"}))
+	logger.Warn("hello", "user_id", 123)
+
+	assert.Equal(t, level.Warning, mockWriter.LastLevel)
+
+	msg := parse(t, mockWriter.LastMessage)
+	assert.Equal(t, "asteria", msg.Context["app"])
+	assert.Equal(t, float64(123), msg.Context["user_id"])
+}
+
+func TestConvertLevel(t *testing.T) {
+	var testCases = map[slog.Level]level.Level{
+		slog.LevelDebug - 4:  level.Debug,
+		slog.LevelDebug:      level.Debug,
+		slog.LevelInfo:       level.Info,
+		slog.LevelInfo + 2:   level.Notice,
+		slog.LevelWarn:       level.Warning,
+		slog.LevelError:      level.Error,
+		slog.LevelError + 4:  level.Critical,
+		slog.LevelError + 8:  level.Alert,
+		slog.LevelError + 20: level.Emergency,
+	}
+	for tc, expected := range testCases {
+		assert.Equal(t, expected, slogh.ConvertLevel(tc))
+	}
+
+	for _, le := range []level.Level{level.Debug, level.Info, level.Notice, level.Warning, level.Error, level.Critical, level.Alert, level.Emergency} {
+		assert.Equal(t, le, slogh.ConvertLevel(slogh.SlogLevel(le)))
+	}
+}
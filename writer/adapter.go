@@ -0,0 +1,81 @@
+package writer
+
+import (
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+)
+
+// EventWriterFunc is an adapter to allow the use of ordinary functions as EventWriter and Writer,
+// such as writing the events to database
+type EventWriterFunc func(evt event.Event, message []byte) error
+
+// WriteEvent call fn(evt, message)
+func (fn EventWriterFunc) WriteEvent(evt event.Event, message []byte) error {
+	return fn(evt, message)
+}
+
+// Write call fn with an event built from the message
+func (fn EventWriterFunc) Write(le level.Level, module string, message string) error {
+	return fn(messageEvent(le, module, message), []byte(message))
+}
+
+func (fn EventWriterFunc) ReOpen() error {
+	return nil
+}
+
+func (fn EventWriterFunc) Close() error {
+	return nil
+}
+
+// AsWriter convert an EventWriter to Writer, so that it can be used as the writer of logger
+//
+// Loggers pass the event to WriteEvent directly, for others calling Write, an event is built from the message,
+// which only has Time, Level, Module and the message as Messages
+func AsWriter(ew EventWriter) Writer {
+	if w, ok := ew.(Writer); ok {
+		return w
+	}
+
+	return eventWriterAdapter{ew}
+}
+
+// AsEventWriter convert a Writer to EventWriter, the event is ignored and the message is written
+func AsEventWriter(w Writer) EventWriter {
+	if ew, ok := w.(EventWriter); ok {
+		return ew
+	}
+
+	return writerAdapter{w}
+}
+
+type eventWriterAdapter struct {
+	EventWriter
+}
+
+func (w eventWriterAdapter) Write(le level.Level, module string, message string) error {
+	return w.WriteEvent(messageEvent(le, module, message), []byte(message))
+}
+
+type writerAdapter struct {
+	Writer
+}
+
+func (w writerAdapter) WriteEvent(evt event.Event, message []byte) error {
+	return writeBytes(w.Writer, evt.Level, evt.Module, message)
+}
+
+// messageEvent build an event from the message
+func messageEvent(le level.Level, module string, message string) event.Event {
+	return event.Event{
+		Time:     time.Now(),
+		Module:   module,
+		Level:    le,
+		Messages: []interface{}{message},
+		Fields: event.Fields{
+			CustomFields: make(map[string]interface{}),
+			GlobalFields: make(map[string]interface{}),
+		},
+	}
+}
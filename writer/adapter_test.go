@@ -0,0 +1,111 @@
+package writer_test
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventWriterFunc(t *testing.T) {
+	var events []event.Event
+	var messages []string
+	w := writer.EventWriterFunc(func(evt event.Event, message []byte) error {
+		events = append(events, evt)
+		messages = append(messages, string(message))
+		return nil
+	})
+
+	logger := log.Module("asteria.adapter").Formatter(formatter.NewJSONFormatter()).Writer(w)
+	logger.WithFields(log.Fields{"user_id": 123}).Error("user created")
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, level.Error, events[0].Level)
+	assert.Equal(t, "asteria.adapter", events[0].Module)
+	assert.Equal(t, []interface{}{"user created"}, events[0].Messages)
+	assert.Equal(t, 123, events[0].Fields.CustomFields["user_id"])
+	assert.Contains(t, messages[0], `"message":"user created"`)
+
+	// called by Write, the event is built from the message
+	assert.NoError(t, w.Write(level.Info, "asteria.user", "hello"))
+	assert.Equal(t, level.Info, events[1].Level)
+	assert.Equal(t, "asteria.user", events[1].Module)
+	assert.Equal(t, []interface{}{"hello"}, events[1].Messages)
+	assert.Equal(t, "hello", messages[1])
+}
+
+type recordEventWriter struct {
+	events      []event.Event
+	reOpenCount int
+	closeCount  int
+}
+
+func (w *recordEventWriter) WriteEvent(evt event.Event, message []byte) error {
+	w.events = append(w.events, evt)
+	return nil
+}
+
+func (w *recordEventWriter) ReOpen() error {
+	w.reOpenCount++
+	return nil
+}
+
+func (w *recordEventWriter) Close() error {
+	w.closeCount++
+	return nil
+}
+
+func TestAsWriter(t *testing.T) {
+	ew := &recordEventWriter{}
+	w := writer.AsWriter(ew)
+
+	assert.NoError(t, w.Write(level.Warning, "asteria.user", "hello"))
+	assert.Equal(t, "asteria.user", ew.events[0].Module)
+	assert.Equal(t, []interface{}{"hello"}, ew.events[0].Messages)
+
+	// the EventWriter which is also a Writer is returned directly
+	fw := writer.NewFormattedWriter(&RecordWriter{}, formatter.NewJSONFormatter())
+	assert.Equal(t, fw, writer.AsWriter(fw))
+
+	assert.NoError(t, w.ReOpen())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 1, ew.reOpenCount)
+	assert.Equal(t, 1, ew.closeCount)
+}
+
+func TestAsEventWriter(t *testing.T) {
+	rw := &RecordWriter{}
+	ew := writer.AsEventWriter(rw)
+
+	assert.NoError(t, ew.WriteEvent(testEvent(), []byte("formatted by logger")))
+	assert.Equal(t, "formatted by logger", rw.Messages[0])
+
+	stack := writer.NewStackWriter()
+	assert.Equal(t, stack, writer.AsEventWriter(stack))
+}
+
+func TestGELFUDPWriter_WriteEvent(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	w := writer.NewGELFUDPWriter(conn.LocalAddr().String()).
+		Compression(writer.GELFCompressNone).
+		Formatter(formatter.NewGELFFormatter().Host("web-01"))
+
+	assert.NoError(t, w.WriteEvent(testEvent(), []byte("formatted by logger")))
+
+	var rs map[string]interface{}
+	assert.NoError(t, json.Unmarshal(readDatagram(t, conn), &rs))
+	assert.Equal(t, "web-01", rs["host"])
+	assert.Equal(t, "user created", rs["short_message"])
+	assert.Equal(t, float64(123), rs["_user_id"])
+
+	assert.NoError(t, w.Close())
+}
@@ -0,0 +1,265 @@
+package writer
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+)
+
+// ErrWriterClosed is returned when writing to a closed writer
+var ErrWriterClosed = errors.New("writer has been closed")
+
+// OverflowPolicy decide what to do when the queue of AsyncWriter is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock block the caller until the queue has free space
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discard the message being written
+	OverflowDropNewest
+	// OverflowDropOldest discard the oldest message in the queue
+	OverflowDropOldest
+	// OverflowDropBelowLevel discard the message if it is less severe than the drop level, otherwise block
+	OverflowDropBelowLevel
+)
+
+type asyncMessage struct {
+	le      level.Level
+	module  string
+	message string
+	// evt is set for messages written by WriteEvent
+	evt *event.Event
+}
+
+// AsyncWriter is a writer which write logs to the underlying writer in a background goroutine
+type AsyncWriter struct {
+	// keep 64-bit counters first to guarantee alignment for atomic operations
+	dropped uint64
+	failed  uint64
+
+	writer    Writer
+	queue     chan asyncMessage
+	policy    OverflowPolicy
+	dropLevel level.Level
+
+	errorHandler     func(err error)
+	errorHandlerLock sync.RWMutex
+
+	pending     int
+	pendingCond *sync.Cond
+
+	closed bool
+	done   chan struct{}
+
+	lock sync.RWMutex
+}
+
+// NewAsyncWriter create a new AsyncWriter with a bounded queue
+func NewAsyncWriter(w Writer, queueSize int, policy OverflowPolicy) *AsyncWriter {
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	wr := &AsyncWriter{
+		writer:      w,
+		queue:       make(chan asyncMessage, queueSize),
+		policy:      policy,
+		dropLevel:   level.Warning,
+		pendingCond: sync.NewCond(&sync.Mutex{}),
+		done:        make(chan struct{}),
+	}
+
+	go wr.consume()
+
+	return wr
+}
+
+// DropLevel set the level for OverflowDropBelowLevel policy, messages less severe than it will be dropped when queue is full
+func (writer *AsyncWriter) DropLevel(le level.Level) *AsyncWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.dropLevel = le
+	return writer
+}
+
+// ErrorHandler set a handler for errors returned by the underlying writer
+func (writer *AsyncWriter) ErrorHandler(fn func(err error)) *AsyncWriter {
+	writer.errorHandlerLock.Lock()
+	defer writer.errorHandlerLock.Unlock()
+
+	writer.errorHandler = fn
+	return writer
+}
+
+// Dropped return the count of messages dropped because the queue is full
+func (writer *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+// Failed return the count of messages which the underlying writer failed to write
+func (writer *AsyncWriter) Failed() uint64 {
+	return atomic.LoadUint64(&writer.failed)
+}
+
+// Write push the message to queue
+func (writer *AsyncWriter) Write(le level.Level, module string, message string) error {
+	return writer.push(asyncMessage{le: le, module: module, message: message})
+}
+
+// WriteEvent push a copy of the event and message to queue, the underlying writer receive the event
+// if it is an EventWriter (such as FormattedWriter)
+func (writer *AsyncWriter) WriteEvent(evt event.Event, message []byte) error {
+	evt = copyEvent(evt)
+	return writer.push(asyncMessage{le: evt.Level, module: evt.Module, message: string(message), evt: &evt})
+}
+
+func (writer *AsyncWriter) push(msg asyncMessage) error {
+	writer.lock.RLock()
+	defer writer.lock.RUnlock()
+
+	if writer.closed {
+		return ErrWriterClosed
+	}
+
+	le := msg.le
+
+	writer.addPending(1)
+	select {
+	case writer.queue <- msg:
+		return nil
+	default:
+	}
+
+	switch writer.policy {
+	case OverflowDropNewest:
+		writer.drop()
+	case OverflowDropOldest:
+		for {
+			select {
+			case writer.queue <- msg:
+				return nil
+			default:
+			}
+
+			select {
+			case <-writer.queue:
+				writer.drop()
+			default:
+			}
+		}
+	case OverflowDropBelowLevel:
+		if le > writer.dropLevel {
+			writer.drop()
+			return nil
+		}
+
+		writer.queue <- msg
+	default:
+		writer.queue <- msg
+	}
+
+	return nil
+}
+
+// Flush block until all messages in queue have been written
+func (writer *AsyncWriter) Flush() {
+	writer.pendingCond.L.Lock()
+	defer writer.pendingCond.L.Unlock()
+
+	for writer.pending > 0 {
+		writer.pendingCond.Wait()
+	}
+}
+
+// ReOpen flush the queue and reopen the underlying writer
+func (writer *AsyncWriter) ReOpen() error {
+	writer.Flush()
+	return writer.writer.ReOpen()
+}
+
+// Close stop accepting new messages, drain the queue and close the underlying writer
+func (writer *AsyncWriter) Close() error {
+	writer.lock.Lock()
+	if writer.closed {
+		writer.lock.Unlock()
+		return nil
+	}
+
+	writer.closed = true
+	close(writer.queue)
+	writer.lock.Unlock()
+
+	<-writer.done
+
+	return writer.writer.Close()
+}
+
+func (writer *AsyncWriter) consume() {
+	defer close(writer.done)
+
+	for msg := range writer.queue {
+		var err error
+		if msg.evt != nil {
+			err = writeEvent(writer.writer, *msg.evt, []byte(msg.message))
+		} else {
+			err = writer.writer.Write(msg.le, msg.module, msg.message)
+		}
+
+		if err != nil {
+			atomic.AddUint64(&writer.failed, 1)
+			if handler := writer.getErrorHandler(); handler != nil {
+				handler(err)
+			}
+		}
+
+		writer.addPending(-1)
+	}
+}
+
+func (writer *AsyncWriter) drop() {
+	atomic.AddUint64(&writer.dropped, 1)
+	writer.addPending(-1)
+}
+
+func (writer *AsyncWriter) addPending(delta int) {
+	writer.pendingCond.L.Lock()
+	defer writer.pendingCond.L.Unlock()
+
+	writer.pending += delta
+	if writer.pending <= 0 {
+		writer.pendingCond.Broadcast()
+	}
+}
+
+func (writer *AsyncWriter) getErrorHandler() func(err error) {
+	writer.errorHandlerLock.RLock()
+	defer writer.errorHandlerLock.RUnlock()
+
+	return writer.errorHandler
+}
+
+// copyEvent copy the fields and messages of event, so that the caller can reuse them after returned
+func copyEvent(evt event.Event) event.Event {
+	res := evt
+	res.Fields = event.Fields{
+		CustomFields: make(map[string]interface{}, len(evt.Fields.CustomFields)),
+		GlobalFields: make(map[string]interface{}, len(evt.Fields.GlobalFields)),
+	}
+
+	for k, v := range evt.Fields.CustomFields {
+		res.Fields.CustomFields[k] = v
+	}
+
+	for k, v := range evt.Fields.GlobalFields {
+		res.Fields.GlobalFields[k] = v
+	}
+
+	res.Messages = append([]interface{}(nil), evt.Messages...)
+	res.TypedFields = append([]event.Field(nil), evt.TypedFields...)
+
+	return res
+}
@@ -0,0 +1,133 @@
+package writer_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+type BlockingWriter struct {
+	entered  chan struct{}
+	release  chan struct{}
+	messages []string
+	closed   bool
+
+	lock sync.Mutex
+}
+
+func NewBlockingWriter() *BlockingWriter {
+	return &BlockingWriter{entered: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (w *BlockingWriter) Write(le level.Level, module string, message string) error {
+	w.entered <- struct{}{}
+	<-w.release
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.messages = append(w.messages, message)
+	return nil
+}
+
+func (w *BlockingWriter) ReOpen() error {
+	return nil
+}
+
+func (w *BlockingWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.closed = true
+	return nil
+}
+
+func (w *BlockingWriter) Messages() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]string{}, w.messages...)
+}
+
+func TestAsyncWriter_Write(t *testing.T) {
+	m := &MockWriter{}
+	aw := writer.NewAsyncWriter(m, 10, writer.OverflowBlock)
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, aw.Write(level.Debug, "", "Hello, world"))
+	}
+
+	aw.Flush()
+	assert.Equal(t, 100, m.WriteCount)
+	assert.Equal(t, uint64(0), aw.Dropped())
+
+	assert.NoError(t, aw.ReOpen())
+	assert.Equal(t, 1, m.ReOpenCount)
+
+	assert.NoError(t, aw.Close())
+	assert.Equal(t, 1, m.CloseCount)
+	assert.Equal(t, writer.ErrWriterClosed, aw.Write(level.Debug, "", "Hello, world"))
+}
+
+func TestAsyncWriter_DropNewest(t *testing.T) {
+	bw := NewBlockingWriter()
+	aw := writer.NewAsyncWriter(bw, 2, writer.OverflowDropNewest)
+
+	assert.NoError(t, aw.Write(level.Debug, "", "1"))
+	<-bw.entered
+
+	for _, msg := range []string{"2", "3", "4", "5"} {
+		assert.NoError(t, aw.Write(level.Debug, "", msg))
+	}
+
+	close(bw.release)
+	assert.NoError(t, aw.Close())
+
+	assert.Equal(t, []string{"1", "2", "3"}, bw.Messages())
+	assert.Equal(t, uint64(2), aw.Dropped())
+	assert.True(t, bw.closed)
+}
+
+func TestAsyncWriter_DropOldest(t *testing.T) {
+	bw := NewBlockingWriter()
+	aw := writer.NewAsyncWriter(bw, 2, writer.OverflowDropOldest)
+
+	assert.NoError(t, aw.Write(level.Debug, "", "1"))
+	<-bw.entered
+
+	for _, msg := range []string{"2", "3", "4", "5"} {
+		assert.NoError(t, aw.Write(level.Debug, "", msg))
+	}
+
+	close(bw.release)
+	aw.Flush()
+
+	assert.Equal(t, []string{"1", "4", "5"}, bw.Messages())
+	assert.Equal(t, uint64(2), aw.Dropped())
+}
+
+func TestAsyncWriter_DropBelowLevel(t *testing.T) {
+	bw := NewBlockingWriter()
+	aw := writer.NewAsyncWriter(bw, 1, writer.OverflowDropBelowLevel).DropLevel(level.Warning)
+
+	assert.NoError(t, aw.Write(level.Error, "", "1"))
+	<-bw.entered
+
+	assert.NoError(t, aw.Write(level.Error, "", "2"))
+	assert.NoError(t, aw.Write(level.Debug, "", "3"))
+	assert.NoError(t, aw.Write(level.Info, "", "4"))
+
+	go func() {
+		close(bw.release)
+	}()
+
+	// warning is not below the drop level, so it blocks until the queue has free space
+	assert.NoError(t, aw.Write(level.Warning, "", "5"))
+	aw.Flush()
+
+	assert.Equal(t, []string{"1", "2", "5"}, bw.Messages())
+	assert.Equal(t, uint64(2), aw.Dropped())
+}
@@ -0,0 +1,115 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/asteria/misc"
+)
+
+type elasticsearchDocument struct {
+	Timestamp string `json:"@timestamp"`
+	Level     string `json:"level"`
+	Module    string `json:"module"`
+	Message   string `json:"message"`
+}
+
+// NewElasticsearchWriter create a HTTPBatchWriter which send logs to Elasticsearch _bulk API, url is like http://localhost:9200
+//
+// index supports strftime-style pattern, such as logs-%Y.%m.%d
+func NewElasticsearchWriter(ctx context.Context, url string, index string) *HTTPBatchWriter {
+	return NewHTTPBatchWriter(ctx, strings.TrimRight(url, "/")+"/_bulk", ElasticsearchEncoder(index)).
+		ResponseChecker(ElasticsearchResponseChecker)
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// ElasticsearchResponseChecker check the response of _bulk API, which response with 200 even if some documents failed,
+// the documents failed with 429 or 5xx are retried, others are reported as rejected
+func ElasticsearchResponseChecker(entries []BatchEntry, body []byte) ([]BatchEntry, error) {
+	var resp elasticsearchBulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid bulk response: %v", err)
+	}
+
+	if !resp.Errors {
+		return nil, nil
+	}
+
+	var retry []BatchEntry
+	var rejected int
+	var reason string
+	for i, item := range resp.Items {
+		if i >= len(entries) {
+			break
+		}
+
+		for _, result := range item {
+			switch {
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, entries[i])
+			case result.Status >= 300:
+				rejected++
+				if reason == "" {
+					reason = string(result.Error)
+				}
+			}
+		}
+	}
+
+	if rejected > 0 {
+		return retry, fmt.Errorf("%d documents rejected by elasticsearch: %s", rejected, reason)
+	}
+
+	return retry, nil
+}
+
+// ElasticsearchEncoder encode the entries for Elasticsearch _bulk API
+//
+// If the message is a JSON object (formatted by JSONFormatter), it will be used as the document directly.
+// The index is generated with the time of entry, which is the time of event for the entries written by loggers
+func ElasticsearchEncoder(index string) BatchEncoder {
+	return func(entries []BatchEntry) ([]byte, string, error) {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+
+		for _, e := range entries {
+			action := map[string]map[string]string{"index": {"_index": misc.Strftime(index, e.Time)}}
+			if err := encoder.Encode(action); err != nil {
+				return nil, "", err
+			}
+
+			if message := strings.TrimSpace(e.Message); strings.HasPrefix(message, "{") {
+				size := buf.Len()
+				if err := json.Compact(&buf, []byte(message)); err == nil {
+					buf.WriteByte('\n')
+					continue
+				}
+
+				buf.Truncate(size)
+			}
+
+			if err := encoder.Encode(elasticsearchDocument{
+				Timestamp: e.Time.Format(time.RFC3339Nano),
+				Level:     e.Level.GetLevelName(),
+				Module:    e.Module,
+				Message:   e.Message,
+			}); err != nil {
+				return nil, "", err
+			}
+		}
+
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+}
@@ -0,0 +1,66 @@
+package writer
+
+import (
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/misc"
+)
+
+// EventWriter is a writer which receive the structured event along with the message formatted by logger
+//
+// When the writer of a logger implements EventWriter, WriteEvent is called instead of Write.
+// The message is only valid during the call, copy it if the writer holds it after returning
+type EventWriter interface {
+	WriteEvent(evt event.Event, message []byte) error
+	ReOpen() error
+	Close() error
+}
+
+// writeEvent write the event to w, the message is used if w is not an EventWriter
+func writeEvent(w Writer, evt event.Event, message []byte) error {
+	if ew, ok := w.(EventWriter); ok {
+		return ew.WriteEvent(evt, message)
+	}
+
+	return writeBytes(w, evt.Level, evt.Module, message)
+}
+
+// FormattedWriter format the event with its own formatter before writing to the underlying writer
+//
+// It is used with StackWriter to write different formats to each destination from one logger,
+// for example, colorful text to stdout and json to file
+type FormattedWriter struct {
+	writer    Writer
+	formatter formatter.Formatter
+}
+
+// NewFormattedWriter create a new FormattedWriter
+func NewFormattedWriter(w Writer, f formatter.Formatter) *FormattedWriter {
+	return &FormattedWriter{writer: w, formatter: f}
+}
+
+// WriteEvent format the event and write it to the underlying writer
+func (writer *FormattedWriter) WriteEvent(evt event.Event, message []byte) error {
+	buf := misc.GetBuffer()
+	defer misc.PutBuffer(buf)
+
+	*buf = formatter.AppendFormat(writer.formatter, *buf, evt)
+	return writeEvent(writer.writer, evt, *buf)
+}
+
+// Write format an event built from the message, which only has Time, Level, Module and the message
+//
+// Loggers and the writers wrapping others (StackWriter, AsyncWriter, FailoverWriter) pass the event to WriteEvent,
+// Write is only called when the writer is wrapped by a writer not forwarding events
+func (writer *FormattedWriter) Write(le level.Level, module string, message string) error {
+	return writer.WriteEvent(messageEvent(le, module, message), []byte(message))
+}
+
+func (writer *FormattedWriter) ReOpen() error {
+	return writer.writer.ReOpen()
+}
+
+func (writer *FormattedWriter) Close() error {
+	return writer.writer.Close()
+}
@@ -0,0 +1,116 @@
+package writer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+type RecordWriter struct {
+	Messages []string
+	MockWriter
+}
+
+func (m *RecordWriter) Write(le level.Level, module string, message string) error {
+	m.Messages = append(m.Messages, message)
+	return m.MockWriter.Write(le, module, message)
+}
+
+func testEvent() event.Event {
+	return event.Event{
+		Time:     time.Date(2019, 7, 17, 17, 5, 4, 0, time.UTC),
+		Module:   "asteria.user",
+		Level:    level.Error,
+		Messages: []interface{}{"user created"},
+		Fields: event.Fields{
+			CustomFields: map[string]interface{}{"user_id": 123},
+			GlobalFields: map[string]interface{}{},
+		},
+	}
+}
+
+func TestFormattedWriter(t *testing.T) {
+	rw := &RecordWriter{}
+	fw := writer.NewFormattedWriter(rw, formatter.NewJSONFormatter())
+
+	assert.NoError(t, fw.WriteEvent(testEvent(), []byte("formatted by logger")))
+	assert.Contains(t, rw.Messages[0], `"message":"user created"`)
+
+	// no event to format, an event is built from the message
+	assert.NoError(t, fw.Write(level.Error, "asteria.user", "formatted by logger"))
+	assert.Contains(t, rw.Messages[1], `"message":"formatted by logger"`)
+
+	assert.NoError(t, fw.ReOpen())
+	assert.NoError(t, fw.Close())
+	assert.Equal(t, 1, rw.ReOpenCount)
+	assert.Equal(t, 1, rw.CloseCount)
+}
+
+func TestStackWriter_WriteEvent(t *testing.T) {
+	console := &RecordWriter{}
+	file := &RecordWriter{}
+	plain := &RecordWriter{}
+
+	stack := writer.NewStackWriter()
+	stack.PushWithFormatter(console, formatter.NewDefaultFormatter(false))
+	stack.PushWithFormatter(file, formatter.NewJSONFormatter(), level.Error)
+	stack.PushWithLevels(plain)
+
+	assert.NoError(t, stack.WriteEvent(testEvent(), []byte("formatted by logger")))
+	assert.Contains(t, console.Messages[0], "ERROR asteria.user user created")
+	assert.Contains(t, file.Messages[0], `"module":"asteria.user"`)
+	assert.Equal(t, "formatted by logger", plain.Messages[0])
+
+	// the global fields prefixed by one formatter do not leak into the others
+	logfmt := &RecordWriter{}
+	stack = writer.NewStackWriter()
+	stack.PushWithFormatter(console, formatter.NewDefaultFormatter(false))
+	stack.PushWithFormatter(logfmt, formatter.NewLogfmtFormatter())
+
+	evt := testEvent()
+	evt.Fields.GlobalFields["file"] = "user.go"
+	assert.NoError(t, stack.WriteEvent(evt, []byte("formatted by logger")))
+	assert.Contains(t, console.Messages[1], `"#file":"user.go"`)
+	assert.Contains(t, logfmt.Messages[0], "file=user.go user_id=123")
+	assert.NotContains(t, logfmt.Messages[0], "#file")
+	stack = writer.NewStackWriter()
+	stack.PushWithFormatter(console, formatter.NewDefaultFormatter(false))
+	stack.PushWithFormatter(file, formatter.NewJSONFormatter(), level.Error)
+
+	evt = testEvent()
+	evt.Level = level.Debug
+	assert.NoError(t, stack.WriteEvent(evt, []byte("formatted by logger")))
+	assert.Len(t, console.Messages, 3)
+	assert.Len(t, file.Messages, 1)
+}
+
+func TestFormattedWriter_Wrapped(t *testing.T) {
+	async := &RecordWriter{}
+	failover := &RecordWriter{}
+
+	aw := writer.NewAsyncWriter(writer.NewFormattedWriter(async, formatter.NewLogfmtFormatter()), 10, writer.OverflowBlock)
+	fw := writer.NewFailoverWriter(
+		writer.NewFormattedWriter(&FailingWriter{Err: errors.New("disk full")}, formatter.NewJSONFormatter()),
+		writer.NewFormattedWriter(failover, formatter.NewLogfmtFormatter()),
+	)
+
+	stack := writer.NewStackWriter()
+	stack.PushWithLevels(aw)
+	stack.PushWithLevels(fw)
+
+	evt := testEvent()
+	assert.NoError(t, stack.WriteEvent(evt, []byte("formatted by logger")))
+
+	// the event queued is not affected by changes after returned
+	evt.Fields.CustomFields["user_id"] = 456
+	assert.NoError(t, aw.Close())
+
+	assert.Equal(t, []string{`time=2019-07-17T17:05:04Z level=error module=asteria.user msg="user created" user_id=123`}, async.Messages)
+	assert.Equal(t, []string{`time=2019-07-17T17:05:04Z level=error module=asteria.user msg="user created" user_id=123`}, failover.Messages)
+}
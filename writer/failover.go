@@ -0,0 +1,211 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+)
+
+// ErrNoHealthyWriter is returned when all writers of FailoverWriter are in cooldown
+var ErrNoHealthyWriter = errors.New("no healthy writer")
+
+// FailoverStat is the health of a writer in FailoverWriter
+type FailoverStat struct {
+	Writer            Writer
+	Healthy           bool
+	ConsecutiveErrors int
+	Writes            uint64
+	Errors            uint64
+	// RetryAt is the time when an unhealthy writer will be probed again
+	RetryAt time.Time
+}
+
+type failoverTarget struct {
+	writer            Writer
+	healthy           bool
+	consecutiveErrors int
+	writes            uint64
+	errors            uint64
+	retryAt           time.Time
+}
+
+// FailoverWriter write messages to the first healthy writer
+//
+// A writer is marked unhealthy after consecutive errors reach the threshold,
+// and it will be probed again after cooldown. If a writer failed, the message is written to the next one
+type FailoverWriter struct {
+	targets   []*failoverTarget
+	active    int
+	switches  uint64
+	threshold int
+	cooldown  time.Duration
+	clock     func() time.Time
+
+	lock sync.Mutex
+}
+
+// NewFailoverWriter create a new FailoverWriter, writers are ordered by priority
+func NewFailoverWriter(writers ...Writer) *FailoverWriter {
+	targets := make([]*failoverTarget, len(writers))
+	for i, w := range writers {
+		targets[i] = &failoverTarget{writer: w, healthy: true}
+	}
+
+	return &FailoverWriter{
+		targets:   targets,
+		threshold: 3,
+		cooldown:  30 * time.Second,
+		clock:     time.Now,
+	}
+}
+
+// Threshold set the count of consecutive errors to mark a writer unhealthy, default is 3
+func (writer *FailoverWriter) Threshold(n int) *FailoverWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+
+	writer.threshold = n
+	return writer
+}
+
+// Cooldown set the duration before an unhealthy writer to be probed again, default is 30s
+func (writer *FailoverWriter) Cooldown(d time.Duration) *FailoverWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.cooldown = d
+	return writer
+}
+
+// WithClock set the clock for FailoverWriter, mainly used for testing
+func (writer *FailoverWriter) WithClock(clock func() time.Time) *FailoverWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.clock = clock
+	return writer
+}
+
+// Switches return the count of switching between writers
+func (writer *FailoverWriter) Switches() uint64 {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.switches
+}
+
+// Stats return the health of all writers
+func (writer *FailoverWriter) Stats() []FailoverStat {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	stats := make([]FailoverStat, len(writer.targets))
+	for i, t := range writer.targets {
+		stats[i] = FailoverStat{
+			Writer:            t.writer,
+			Healthy:           t.healthy,
+			ConsecutiveErrors: t.consecutiveErrors,
+			Writes:            t.writes,
+			Errors:            t.errors,
+			RetryAt:           t.retryAt,
+		}
+	}
+
+	return stats
+}
+
+// Write the message to the first healthy writer
+func (writer *FailoverWriter) Write(le level.Level, module string, message string) error {
+	return writer.write(func(w Writer) error {
+		return w.Write(le, module, message)
+	})
+}
+
+// WriteEvent write the event to the first healthy writer, the writers implement EventWriter
+// (such as FormattedWriter) receive the event, others receive the message
+func (writer *FailoverWriter) WriteEvent(evt event.Event, message []byte) error {
+	return writer.write(func(w Writer) error {
+		return writeEvent(w, evt, message)
+	})
+}
+
+// write call fn with the first healthy writer, until it succeed
+func (writer *FailoverWriter) write(fn func(w Writer) error) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	now := writer.clock()
+
+	var lastErr error
+	for i, t := range writer.targets {
+		if !t.healthy && now.Before(t.retryAt) {
+			continue
+		}
+
+		err := fn(t.writer)
+		if err == nil {
+			t.healthy = true
+			t.consecutiveErrors = 0
+			t.writes++
+
+			if i != writer.active {
+				writer.active = i
+				writer.switches++
+			}
+
+			return nil
+		}
+
+		lastErr = err
+		t.errors++
+		t.consecutiveErrors++
+		if !t.healthy || t.consecutiveErrors >= writer.threshold {
+			t.healthy = false
+			t.retryAt = now.Add(writer.cooldown)
+		}
+	}
+
+	if lastErr == nil {
+		return ErrNoHealthyWriter
+	}
+
+	return fmt.Errorf("all writers failed, last error: %v", lastErr)
+}
+
+// ReOpen reopen all writers
+func (writer *FailoverWriter) ReOpen() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	var lastErr error
+	for _, t := range writer.targets {
+		if err := t.writer.ReOpen(); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// Close all writers
+func (writer *FailoverWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	var lastErr error
+	for _, t := range writer.targets {
+		if err := t.writer.Close(); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
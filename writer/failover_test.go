@@ -0,0 +1,87 @@
+package writer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+type SwitchableWriter struct {
+	Down bool
+	MockWriter
+}
+
+func (m *SwitchableWriter) Write(le level.Level, module string, message string) error {
+	if m.Down {
+		return errors.New("connection refused")
+	}
+
+	return m.MockWriter.Write(le, module, message)
+}
+
+func TestFailoverWriter_Write(t *testing.T) {
+	now := time.Now()
+	primary := &SwitchableWriter{}
+	secondary := &MockWriter{}
+
+	fw := writer.NewFailoverWriter(primary, secondary).
+		Threshold(2).
+		Cooldown(time.Minute).
+		WithClock(func() time.Time { return now })
+
+	assert.NoError(t, fw.Write(level.Info, "test", "1"))
+	assert.Equal(t, 1, primary.WriteCount)
+
+	// the message is written to secondary when primary failed
+	primary.Down = true
+	assert.NoError(t, fw.Write(level.Info, "test", "2"))
+	assert.Equal(t, 1, secondary.WriteCount)
+	assert.True(t, fw.Stats()[0].Healthy)
+
+	// primary is marked unhealthy after consecutive errors reach threshold
+	assert.NoError(t, fw.Write(level.Info, "test", "3"))
+	assert.Equal(t, 2, secondary.WriteCount)
+
+	stats := fw.Stats()
+	assert.False(t, stats[0].Healthy)
+	assert.Equal(t, 2, stats[0].ConsecutiveErrors)
+	assert.Equal(t, uint64(2), stats[0].Errors)
+	assert.Equal(t, now.Add(time.Minute), stats[0].RetryAt)
+	assert.Equal(t, uint64(2), stats[1].Writes)
+	assert.Equal(t, uint64(1), fw.Switches())
+
+	// primary is skipped during cooldown
+	primary.Down = false
+	assert.NoError(t, fw.Write(level.Info, "test", "4"))
+	assert.Equal(t, 1, primary.WriteCount)
+	assert.Equal(t, 3, secondary.WriteCount)
+
+	// probe primary after cooldown
+	now = now.Add(time.Minute)
+	assert.NoError(t, fw.Write(level.Info, "test", "5"))
+	assert.Equal(t, 2, primary.WriteCount)
+	assert.True(t, fw.Stats()[0].Healthy)
+	assert.Equal(t, uint64(2), fw.Switches())
+}
+
+func TestFailoverWriter_AllFailed(t *testing.T) {
+	now := time.Now()
+	primary := &SwitchableWriter{Down: true}
+	secondary := &SwitchableWriter{Down: true}
+
+	fw := writer.NewFailoverWriter(primary, secondary).
+		Threshold(1).
+		WithClock(func() time.Time { return now })
+
+	assert.Error(t, fw.Write(level.Info, "test", "1"))
+	assert.Equal(t, writer.ErrNoHealthyWriter, fw.Write(level.Info, "test", "2"))
+
+	assert.NoError(t, fw.ReOpen())
+	assert.NoError(t, fw.Close())
+	assert.Equal(t, 1, primary.ReOpenCount)
+	assert.Equal(t, 1, secondary.CloseCount)
+}
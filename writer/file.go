@@ -40,6 +40,16 @@ func (writer *FileWriter) Write(le level.Level, module string, message string) e
 	return err
 }
 
+// WriteBytes write the message to file without converting it to string
+func (writer *FileWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	f, err := writer.open()
+	if err != nil {
+		return err
+	}
+
+	return writeLine(f, message)
+}
+
 // ReOpen reopen a log file
 func (writer *FileWriter) ReOpen() error {
 	if err := writer.Close(); err != nil {
@@ -120,6 +130,11 @@ func (writer *RotatingFileWriter) Write(le level.Level, module string, message s
 	return writer.getWriter(writer.fn(le, module)).Write(le, module, message)
 }
 
+// WriteBytes write the message without converting it to string
+func (writer *RotatingFileWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	return writer.getWriter(writer.fn(le, module)).WriteBytes(le, module, message)
+}
+
 func (writer *RotatingFileWriter) autoGC(ctx context.Context, interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -181,7 +196,21 @@ func (writer *RotatingFileWriter) getWriter(destFile string) *FileWriter {
 	return w
 }
 
+// closeFile close the file and remove it from opened files
+func (writer *RotatingFileWriter) closeFile(filename string) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if f, ok := writer.openedFiles[filename]; ok {
+		_ = f.Close()
+		delete(writer.openedFiles, filename)
+	}
+}
+
 func (writer *RotatingFileWriter) ReOpen() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
 	for _, w := range writer.openedFiles {
 		if err := w.ReOpen(); err != nil {
 			return err
@@ -192,6 +221,9 @@ func (writer *RotatingFileWriter) ReOpen() error {
 }
 
 func (writer *RotatingFileWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
 	for _, w := range writer.openedFiles {
 		if err := w.Close(); err != nil {
 			return err
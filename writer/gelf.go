@@ -0,0 +1,209 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+)
+
+// GELFCompression is the compression type for GELF UDP messages
+type GELFCompression int
+
+const (
+	GELFCompressGzip GELFCompression = iota
+	GELFCompressZlib
+	GELFCompressNone
+)
+
+const (
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+)
+
+// GELFUDPWriter is a LogWriter which send GELF messages to Graylog over UDP
+//
+// The events from loggers are formatted by its own GELFFormatter, so the formatter of logger doesn't matter.
+// Messages passed to Write should be formatted by formatter.GELFFormatter.
+// Messages larger than the chunk size will be split into chunks
+type GELFUDPWriter struct {
+	addr        string
+	compression GELFCompression
+	chunkSize   int
+	formatter   *formatter.GELFFormatter
+
+	conn net.Conn
+	lock sync.Mutex
+}
+
+// NewGELFUDPWriter create a new GELFUDPWriter, messages are compressed with gzip by default
+func NewGELFUDPWriter(addr string) *GELFUDPWriter {
+	return &GELFUDPWriter{
+		addr:        addr,
+		compression: GELFCompressGzip,
+		chunkSize:   1420,
+		formatter:   formatter.NewGELFFormatter(),
+	}
+}
+
+// NewGELFTCPWriter create a NetWriter to send GELF messages to Graylog over TCP, messages are delimited by null byte
+//
+// The events from loggers are formatted by GELFFormatter instead of the formatter of logger, use NetWriter.Formatter
+// to change it, or Formatter(nil) to send the message formatted by logger
+func NewGELFTCPWriter(addr string) *NetWriter {
+	return NewNetWriter("tcp", addr).Framing(nullByteFramer).Formatter(formatter.NewGELFFormatter())
+}
+
+// Compression set the compression type
+func (writer *GELFUDPWriter) Compression(compression GELFCompression) *GELFUDPWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.compression = compression
+	return writer
+}
+
+// ChunkSize set the max size of a datagram, including the chunk header
+func (writer *GELFUDPWriter) ChunkSize(size int) *GELFUDPWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.chunkSize = size
+	return writer
+}
+
+// Formatter set the GELFFormatter for events, such as changing the host
+func (writer *GELFUDPWriter) Formatter(f *formatter.GELFFormatter) *GELFUDPWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.formatter = f
+	return writer
+}
+
+// WriteEvent format the event as GELF message and send it to Graylog
+func (writer *GELFUDPWriter) WriteEvent(evt event.Event, message []byte) error {
+	writer.lock.Lock()
+	f := writer.formatter
+	writer.lock.Unlock()
+
+	return writer.Write(evt.Level, evt.Module, f.Format(evt))
+}
+
+// Write send the message to Graylog
+func (writer *GELFUDPWriter) Write(le level.Level, module string, message string) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	data, err := writer.compress([]byte(message))
+	if err != nil {
+		return err
+	}
+
+	if writer.conn == nil {
+		conn, err := net.Dial("udp", writer.addr)
+		if err != nil {
+			return err
+		}
+
+		writer.conn = conn
+	}
+
+	if len(data) <= writer.chunkSize {
+		_, err := writer.conn.Write(data)
+		return err
+	}
+
+	return writer.writeChunks(data)
+}
+
+func (writer *GELFUDPWriter) writeChunks(data []byte) error {
+	payloadSize := writer.chunkSize - gelfChunkHeaderSize
+	if payloadSize <= 0 {
+		return fmt.Errorf("gelf chunk size %d is too small", writer.chunkSize)
+	}
+
+	count := (len(data) + payloadSize - 1) / payloadSize
+	if count > gelfMaxChunks {
+		return fmt.Errorf("gelf message is too large: %d chunks needed, max is %d", count, gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, writer.chunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payloadSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[i*payloadSize:end]...)
+
+		if _, err := writer.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (writer *GELFUDPWriter) compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch writer.compression {
+	case GELFCompressGzip:
+		w = gzip.NewWriter(&buf)
+	case GELFCompressZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		return data, nil
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ReOpen close the connection, it will be reconnected on next write
+func (writer *GELFUDPWriter) ReOpen() error {
+	return writer.Close()
+}
+
+// Close the connection
+func (writer *GELFUDPWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.conn == nil {
+		return nil
+	}
+
+	err := writer.conn.Close()
+	writer.conn = nil
+	return err
+}
+
+func nullByteFramer(message string) []byte {
+	return []byte(message + "\x00")
+}
@@ -0,0 +1,123 @@
+package writer_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func readDatagram(t *testing.T, conn net.PacketConn) []byte {
+	buf := make([]byte, 8192)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+
+	return buf[:n]
+}
+
+func TestGELFUDPWriter_Write(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	w := writer.NewGELFUDPWriter(conn.LocalAddr().String())
+	assert.NoError(t, w.Write(level.Debug, "", `{"short_message":"Hello"}`))
+
+	gz, err := gzip.NewReader(bytes.NewReader(readDatagram(t, conn)))
+	assert.NoError(t, err)
+	rs, err := ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"short_message":"Hello"}`, string(rs))
+
+	w.Compression(writer.GELFCompressNone)
+	assert.NoError(t, w.Write(level.Debug, "", `{"short_message":"Hello"}`))
+	assert.Equal(t, `{"short_message":"Hello"}`, string(readDatagram(t, conn)))
+
+	assert.NoError(t, w.Close())
+}
+
+func TestGELFUDPWriter_Chunking(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	message := `{"short_message":"` + strings.Repeat("Hello, world ", 20) + `"}`
+
+	w := writer.NewGELFUDPWriter(conn.LocalAddr().String()).Compression(writer.GELFCompressZlib).ChunkSize(32)
+	assert.NoError(t, w.Write(level.Debug, "", message))
+
+	var payload []byte
+	var id []byte
+	for i, count := 0, 1; i < count; i++ {
+		chunk := readDatagram(t, conn)
+		assert.True(t, len(chunk) <= 32)
+		assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+
+		if id == nil {
+			id = chunk[2:10]
+		}
+		assert.Equal(t, id, chunk[2:10])
+		assert.Equal(t, byte(i), chunk[10])
+
+		count = int(chunk[11])
+		payload = append(payload, chunk[12:]...)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(payload))
+	assert.NoError(t, err)
+	rs, err := ioutil.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Equal(t, message, string(rs))
+
+	assert.Error(t, w.Compression(writer.GELFCompressNone).ChunkSize(13).Write(level.Debug, "", strings.Repeat("x", 200)))
+	assert.NoError(t, w.Close())
+}
+
+func TestGELFTCPWriter_Write(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 0, 1024)
+		tmp := make([]byte, 1024)
+		for bytes.Count(buf, []byte{0}) < 2 {
+			n, err := conn.Read(tmp)
+			if err != nil {
+				return
+			}
+			buf = append(buf, tmp[:n]...)
+		}
+
+		received <- buf
+	}()
+
+	w := writer.NewGELFTCPWriter(listener.Addr().String())
+	assert.NoError(t, w.Write(level.Debug, "", `{"short_message":"1"}`))
+	assert.NoError(t, w.Write(level.Debug, "", `{"short_message":"2"}`))
+
+	select {
+	case rs := <-received:
+		assert.Equal(t, "{\"short_message\":\"1\"}\x00{\"short_message\":\"2\"}\x00", string(rs))
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	assert.NoError(t, w.Close())
+}
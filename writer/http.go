@@ -0,0 +1,489 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+)
+
+// BatchEntry is a log message waiting to be sent by HTTPBatchWriter
+type BatchEntry struct {
+	Time    time.Time   `json:"time"`
+	Level   level.Level `json:"level"`
+	Module  string      `json:"module"`
+	Message string      `json:"message"`
+}
+
+// BatchEncoder encode the entries to request body
+type BatchEncoder func(entries []BatchEntry) (body []byte, contentType string, err error)
+
+// BatchResponseChecker check the response body of a request succeeded, for the endpoints which report
+// errors of each entry (such as Elasticsearch _bulk API), return the entries to be retried and the error
+// for the entries rejected
+type BatchResponseChecker func(entries []BatchEntry, body []byte) (retry []BatchEntry, err error)
+
+// httpStatusError is returned when the endpoint response with a non-2xx status code
+type httpStatusError struct {
+	statusCode int
+	body       string
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected http status %d: %s", e.statusCode, e.body)
+}
+
+// BatchError is the aggregated error of HTTPBatchWriter.Flush
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d batches failed: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// retryable return whether the request should be retried, only 5xx and network errors are retryable
+func retryable(err error) bool {
+	if statusErr, ok := err.(httpStatusError); ok {
+		return statusErr.statusCode >= 500
+	}
+
+	if _, ok := err.(batchRejectedError); ok {
+		return false
+	}
+
+	return true
+}
+
+// HTTPBatchWriter is a LogWriter which batch the messages and POST them to an HTTP endpoint
+//
+// Batches are sent when the size reached or on every interval. Requests failed with 5xx or network
+// errors will be retried with jittered backoff, and spooled to a local file if all retries failed.
+type HTTPBatchWriter struct {
+	ctx     context.Context
+	url     string
+	encoder BatchEncoder
+	checker BatchResponseChecker
+	client  *http.Client
+	headers http.Header
+
+	batchSize  int
+	interval   time.Duration
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	spoolFile  string
+
+	entries []BatchEntry
+	trigger chan struct{}
+
+	errorHandler func(err error)
+
+	lock     sync.Mutex
+	sendLock sync.Mutex
+}
+
+// NewHTTPBatchWriter create a new HTTPBatchWriter, the background flushing will stop when ctx is done
+func NewHTTPBatchWriter(ctx context.Context, url string, encoder BatchEncoder) *HTTPBatchWriter {
+	wr := &HTTPBatchWriter{
+		ctx:        ctx,
+		url:        url,
+		encoder:    encoder,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		headers:    make(http.Header),
+		batchSize:  100,
+		interval:   time.Second,
+		maxRetries: 3,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+		trigger:    make(chan struct{}, 1),
+	}
+
+	wr.autoFlush(ctx)
+	return wr
+}
+
+// Header set a header for every request, such as Authorization
+func (writer *HTTPBatchWriter) Header(key, value string) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.headers.Set(key, value)
+	return writer
+}
+
+// ResponseChecker set a checker for the response body of requests succeeded
+func (writer *HTTPBatchWriter) ResponseChecker(checker BatchResponseChecker) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.checker = checker
+	return writer
+}
+
+// Client set the http client used to send requests
+func (writer *HTTPBatchWriter) Client(client *http.Client) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.client = client
+	return writer
+}
+
+// BatchSize set the max count of messages in a batch, size <= 0 for no limit,
+// the messages are sent in one batch by interval or Flush
+func (writer *HTTPBatchWriter) BatchSize(size int) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.batchSize = size
+	return writer
+}
+
+// Interval set the interval of flushing
+func (writer *HTTPBatchWriter) Interval(interval time.Duration) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.interval = interval
+	return writer
+}
+
+// Retry set the max retries and the backoff between retries
+func (writer *HTTPBatchWriter) Retry(maxRetries int, minBackoff, maxBackoff time.Duration) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.maxRetries = maxRetries
+	writer.minBackoff = minBackoff
+	writer.maxBackoff = maxBackoff
+	return writer
+}
+
+// Spool set a local file to keep the batches which failed to send, they will be resent on next flushing
+func (writer *HTTPBatchWriter) Spool(filename string) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.spoolFile = filename
+	return writer
+}
+
+// ErrorHandler set a handler for errors of flushing in background
+func (writer *HTTPBatchWriter) ErrorHandler(fn func(err error)) *HTTPBatchWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.errorHandler = fn
+	return writer
+}
+
+// Write add the message to current batch
+func (writer *HTTPBatchWriter) Write(le level.Level, module string, message string) error {
+	return writer.add(BatchEntry{Time: time.Now(), Level: le, Module: module, Message: message})
+}
+
+// WriteEvent add the message to current batch with the time of event
+func (writer *HTTPBatchWriter) WriteEvent(evt event.Event, message []byte) error {
+	return writer.add(BatchEntry{Time: evt.Time, Level: evt.Level, Module: evt.Module, Message: string(message)})
+}
+
+func (writer *HTTPBatchWriter) add(entry BatchEntry) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.entries = append(writer.entries, entry)
+
+	if writer.batchSize > 0 && len(writer.entries) >= writer.batchSize {
+		select {
+		case writer.trigger <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush send all messages in current batch and the spool file
+//
+// All batches are tried even if some of them failed, the batches failed with retryable errors are
+// spooled if spool file set, others are dropped. The errors are aggregated to a *BatchError
+func (writer *HTTPBatchWriter) Flush() error {
+	writer.sendLock.Lock()
+	defer writer.sendLock.Unlock()
+
+	writer.lock.Lock()
+	entries := writer.entries
+	writer.entries = nil
+	batchSize, spoolFile := writer.batchSize, writer.spoolFile
+	writer.lock.Unlock()
+
+	if spoolFile != "" {
+		if err := writer.flushSpool(spoolFile, batchSize); err != nil {
+			return writer.spool(spoolFile, entries, err)
+		}
+	}
+
+	var errs []error
+	for len(entries) > 0 {
+		n := batchSize
+		if n <= 0 || n > len(entries) {
+			n = len(entries)
+		}
+
+		if pending, err := writer.send(entries[:n]); err != nil {
+			errs = append(errs, err)
+
+			if len(pending) > 0 && spoolFile != "" {
+				if err := writer.spool(spoolFile, pending, nil); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+
+		entries = entries[n:]
+	}
+
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+
+	return nil
+}
+
+// ReOpen send all messages in current batch
+func (writer *HTTPBatchWriter) ReOpen() error {
+	return writer.Flush()
+}
+
+// Close send all messages in current batch
+func (writer *HTTPBatchWriter) Close() error {
+	return writer.Flush()
+}
+
+func (writer *HTTPBatchWriter) autoFlush(ctx context.Context) {
+	go func() {
+		for {
+			writer.lock.Lock()
+			interval := writer.interval
+			writer.lock.Unlock()
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-writer.trigger:
+				timer.Stop()
+			case <-timer.C:
+			}
+
+			if err := writer.Flush(); err != nil {
+				writer.lock.Lock()
+				handler := writer.errorHandler
+				writer.lock.Unlock()
+
+				if handler != nil {
+					handler(err)
+				}
+			}
+		}
+	}()
+}
+
+// send post the entries to endpoint, retry if failed with 5xx or network errors, or the entries are
+// reported as retryable by checker. The entries not sent but retryable are returned with the error
+func (writer *HTTPBatchWriter) send(entries []BatchEntry) ([]BatchEntry, error) {
+	writer.lock.Lock()
+	client, checker, headers := writer.client, writer.checker, make(http.Header, len(writer.headers))
+	for k, v := range writer.headers {
+		headers[k] = v
+	}
+	maxRetries, minBackoff, maxBackoff := writer.maxRetries, writer.minBackoff, writer.maxBackoff
+	writer.lock.Unlock()
+
+	var rejected []string
+	result := func(pending []BatchEntry, err error) ([]BatchEntry, error) {
+		if len(rejected) == 0 {
+			return pending, err
+		}
+
+		if err != nil {
+			rejected = append(rejected, err.Error())
+		}
+
+		return pending, batchRejectedError(strings.Join(rejected, "; "))
+	}
+
+	backoff := minBackoff
+	for i := 0; ; i++ {
+		body, contentType, err := writer.encoder(entries)
+		if err != nil {
+			return result(nil, err)
+		}
+
+		respBody, err := writer.post(client, headers, body, contentType, checker != nil)
+		if err == nil && checker != nil {
+			retry, checkErr := checker(entries, respBody)
+			if checkErr != nil {
+				rejected = append(rejected, checkErr.Error())
+			}
+
+			if len(retry) > 0 {
+				entries = retry
+				err = fmt.Errorf("%d entries not accepted", len(retry))
+			}
+		}
+
+		if err == nil {
+			return result(nil, nil)
+		}
+
+		if !retryable(err) {
+			return result(nil, err)
+		}
+
+		if i >= maxRetries {
+			return result(entries, err)
+		}
+
+		// jitter the backoff between [backoff/2, backoff*3/2)
+		sleep := backoff/2 + time.Duration(rand.Int63n(int64(backoff)+1))
+		select {
+		case <-writer.ctx.Done():
+			return result(entries, err)
+		case <-time.After(sleep):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// batchRejectedError is the error for entries rejected, which will never succeed
+type batchRejectedError string
+
+func (e batchRejectedError) Error() string {
+	return string(e)
+}
+
+// post send the request, the response body is returned if readBody is true
+func (writer *HTTPBatchWriter) post(client *http.Client, headers http.Header, body []byte, contentType string, readBody bool) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, writer.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, httpStatusError{statusCode: resp.StatusCode, body: string(respBody)}
+	}
+
+	if readBody {
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil, nil
+}
+
+// spool append the entries to spool file, the original error will be returned
+func (writer *HTTPBatchWriter) spool(spoolFile string, entries []BatchEntry, cause error) error {
+	if len(entries) == 0 {
+		return cause
+	}
+
+	f, err := os.OpenFile(spoolFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return cause
+}
+
+// flushSpool resend the entries in spool file, the spool file will be removed if all of them sent
+func (writer *HTTPBatchWriter) flushSpool(spoolFile string, batchSize int) error {
+	f, err := os.Open(spoolFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	entries := make([]BatchEntry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var e BatchEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	_ = f.Close()
+
+	for len(entries) > 0 {
+		n := batchSize
+		if n <= 0 || n > len(entries) {
+			n = len(entries)
+		}
+
+		pending, err := writer.send(entries[:n])
+		entries = entries[n:]
+		if err == nil {
+			continue
+		}
+
+		// the entries rejected by endpoint will never succeed, discard them
+		if len(pending) == 0 {
+			continue
+		}
+
+		// rewrite the spool file with the entries not sent
+		if err := os.Remove(spoolFile); err != nil {
+			return err
+		}
+		_ = writer.spool(spoolFile, append(append([]BatchEntry(nil), pending...), entries...), nil)
+		return err
+	}
+
+	return os.Remove(spoolFile)
+}
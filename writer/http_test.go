@@ -0,0 +1,342 @@
+package writer_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+type batchRecorder struct {
+	batches  [][]writer.BatchEntry
+	headers  []http.Header
+	failures int
+
+	lock sync.Mutex
+}
+
+func (r *batchRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var entries []writer.BatchEntry
+	body, _ := ioutil.ReadAll(req.Body)
+	_ = json.Unmarshal(body, &entries)
+
+	r.batches = append(r.batches, entries)
+	r.headers = append(r.headers, req.Header)
+}
+
+func (r *batchRecorder) Batches() [][]writer.BatchEntry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.batches
+}
+
+func jsonEncoder(entries []writer.BatchEntry) ([]byte, string, error) {
+	body, err := json.Marshal(entries)
+	return body, "application/json", err
+}
+
+func TestHTTPBatchWriter_Write(t *testing.T) {
+	recorder := &batchRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := writer.NewHTTPBatchWriter(ctx, server.URL, jsonEncoder).
+		BatchSize(2).
+		Interval(time.Hour).
+		Header("Authorization", "Bearer token")
+
+	assert.NoError(t, w.Write(level.Debug, "test", "1"))
+	assert.NoError(t, w.Write(level.Error, "test", "2"))
+
+	// batch size reached, it will be sent in background
+	for i := 0; i < 100 && len(recorder.Batches()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.NoError(t, w.Write(level.Debug, "test", "3"))
+	assert.NoError(t, w.Close())
+
+	batches := recorder.Batches()
+	assert.Len(t, batches, 2)
+	assert.Equal(t, "1", batches[0][0].Message)
+	assert.Equal(t, level.Error, batches[0][1].Level)
+	assert.Equal(t, "3", batches[1][0].Message)
+	assert.Equal(t, "Bearer token", recorder.headers[0].Get("Authorization"))
+}
+
+func TestHTTPBatchWriter_Interval(t *testing.T) {
+	recorder := &batchRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := writer.NewHTTPBatchWriter(ctx, server.URL, jsonEncoder).Interval(10 * time.Millisecond)
+	assert.NoError(t, w.Write(level.Debug, "test", "1"))
+
+	for i := 0; i < 100 && len(recorder.Batches()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Len(t, recorder.Batches(), 1)
+}
+
+func TestHTTPBatchWriter_Retry(t *testing.T) {
+	recorder := &batchRecorder{failures: 2}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	w := writer.NewHTTPBatchWriter(context.TODO(), server.URL, jsonEncoder).
+		Interval(time.Hour).
+		Retry(2, time.Millisecond, 5*time.Millisecond)
+
+	assert.NoError(t, w.Write(level.Debug, "test", "1"))
+	assert.NoError(t, w.Flush())
+	assert.Len(t, recorder.Batches(), 1)
+
+	recorder.failures = 3
+	assert.NoError(t, w.Write(level.Debug, "test", "2"))
+	assert.Error(t, w.Flush())
+	assert.Len(t, recorder.Batches(), 1)
+}
+
+func TestHTTPBatchWriter_PartialFailure(t *testing.T) {
+	var lock sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var entries []writer.BatchEntry
+		body, _ := ioutil.ReadAll(req.Body)
+		_ = json.Unmarshal(body, &entries)
+
+		if entries[0].Message == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		messages = append(messages, entries[0].Message)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 10)
+	w := writer.NewHTTPBatchWriter(ctx, server.URL, jsonEncoder).
+		Interval(time.Hour).
+		ErrorHandler(func(err error) { errs <- err })
+
+	assert.NoError(t, w.Write(level.Debug, "test", "1"))
+	assert.NoError(t, w.Write(level.Debug, "test", "bad"))
+	assert.NoError(t, w.Write(level.Debug, "test", "3"))
+
+	// the batches after the failed one are still sent
+	w.BatchSize(1)
+	err := w.Flush()
+	assert.Error(t, err)
+	assert.Len(t, err.(*writer.BatchError).Errors, 1)
+
+	lock.Lock()
+	assert.Equal(t, []string{"1", "3"}, messages)
+	lock.Unlock()
+
+	// batch size reached, the errors of background flushing are passed to error handler
+	assert.NoError(t, w.Write(level.Debug, "test", "bad"))
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "unexpected http status 400")
+	case <-time.After(time.Second):
+		assert.Fail(t, "error handler not called")
+	}
+}
+
+func TestHTTPBatchWriter_Spool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	spoolFile := filepath.Join(dir, "spool.log")
+
+	recorder := &batchRecorder{failures: 2}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	w := writer.NewHTTPBatchWriter(context.TODO(), server.URL, jsonEncoder).
+		Interval(time.Hour).
+		Retry(1, time.Millisecond, time.Millisecond).
+		Spool(spoolFile)
+
+	assert.NoError(t, w.Write(level.Debug, "test", "1"))
+	assert.NoError(t, w.Write(level.Debug, "test", "2"))
+	assert.Error(t, w.Flush())
+
+	_, err = os.Stat(spoolFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.Write(level.Debug, "test", "3"))
+	assert.NoError(t, w.Flush())
+
+	batches := recorder.Batches()
+	assert.Len(t, batches, 2)
+	assert.Equal(t, "1", batches[0][0].Message)
+	assert.Equal(t, "2", batches[0][1].Message)
+	assert.Equal(t, "3", batches[1][0].Message)
+
+	_, err = os.Stat(spoolFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestHTTPBatchWriter_SpoolNoBatchSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	spoolFile := filepath.Join(dir, "spool.log")
+
+	recorder := &batchRecorder{failures: 2}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	w := writer.NewHTTPBatchWriter(context.TODO(), server.URL, jsonEncoder).
+		Interval(time.Hour).
+		BatchSize(0).
+		Retry(1, time.Millisecond, time.Millisecond).
+		Spool(spoolFile)
+
+	assert.NoError(t, w.Write(level.Debug, "test", "1"))
+	assert.NoError(t, w.Write(level.Debug, "test", "2"))
+	assert.Error(t, w.Flush())
+
+	// the spooled entries are sent in one batch
+	done := make(chan error, 1)
+	go func() { done <- w.Flush() }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("flush spool does not finish")
+	}
+
+	batches := recorder.Batches()
+	assert.Len(t, batches, 1)
+	assert.Len(t, batches[0], 2)
+}
+
+func TestLokiEncoder(t *testing.T) {
+	tm := time.Unix(1563353904, 123)
+	body, contentType, err := writer.LokiEncoder(map[string]string{"app": "asteria"})([]writer.BatchEntry{
+		{Time: tm, Level: level.Debug, Module: "user", Message: "1"},
+		{Time: tm, Level: level.Error, Module: "user", Message: "2"},
+		{Time: tm, Level: level.Debug, Module: "user", Message: "3"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{"streams":[
+		{"stream":{"app":"asteria","module":"user","level":"DEBUG"},"values":[["1563353904000000123","1"],["1563353904000000123","3"]]},
+		{"stream":{"app":"asteria","module":"user","level":"ERROR"},"values":[["1563353904000000123","2"]]}
+	]}`, string(body))
+}
+
+func TestElasticsearchWriter(t *testing.T) {
+	var path, body, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rs, _ := ioutil.ReadAll(req.Body)
+		path, body, contentType = req.URL.Path, string(rs), req.Header.Get("Content-Type")
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	w := writer.NewElasticsearchWriter(context.TODO(), server.URL+"/", "logs-%Y.%m.%d").Interval(time.Hour)
+	assert.NoError(t, w.Write(level.Debug, "user", "Hello, world"))
+	assert.NoError(t, w.Write(level.Error, "user", "{\n\"message\": \"Hello, json\"}"))
+	assert.NoError(t, w.Flush())
+
+	assert.Equal(t, "/_bulk", path)
+	assert.Equal(t, "application/x-ndjson", contentType)
+
+	index := `{"index":{"_index":"logs-` + time.Now().Format("2006.01.02") + `"}}`
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, index, lines[0])
+	assert.Contains(t, lines[1], `"level":"DEBUG","module":"user","message":"Hello, world"`)
+	assert.Equal(t, index, lines[2])
+	assert.Equal(t, `{"message":"Hello, json"}`, lines[3])
+}
+
+func TestElasticsearchWriter_BulkErrors(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rs, _ := ioutil.ReadAll(req.Body)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		requests = append(requests, string(rs))
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"errors":true,"items":[
+				{"index":{"status":201}},
+				{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},
+				{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}
+			]}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	w := writer.NewElasticsearchWriter(context.TODO(), server.URL, "logs-%Y.%m.%d").
+		Interval(time.Hour).
+		Retry(1, time.Millisecond, time.Millisecond)
+
+	tm := time.Date(2019, 7, 17, 16, 58, 24, 0, time.UTC)
+	for _, msg := range []string{"created", "invalid", "overloaded"} {
+		assert.NoError(t, w.WriteEvent(event.Event{Time: tm, Level: level.Error, Module: "user"}, []byte(msg)))
+	}
+
+	err := w.Flush()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `1 documents rejected by elasticsearch: {"type":"mapper_parsing_exception"}`)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// only the document failed with 429 is retried
+	assert.Len(t, requests, 2)
+	lines := strings.Split(strings.TrimSpace(requests[1]), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `{"index":{"_index":"logs-2019.07.17"}}`, lines[0])
+	assert.Contains(t, lines[1], `"@timestamp":"2019-07-17T16:58:24Z"`)
+	assert.Contains(t, lines[1], `"message":"overloaded"`)
+}
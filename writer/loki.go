@@ -0,0 +1,52 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+// NewLokiWriter create a HTTPBatchWriter which push logs to Loki, url is like http://localhost:3100/loki/api/v1/push
+//
+// The module and level name are added to labels for every stream
+func NewLokiWriter(ctx context.Context, url string, labels map[string]string) *HTTPBatchWriter {
+	return NewHTTPBatchWriter(ctx, url, LokiEncoder(labels))
+}
+
+// LokiEncoder encode the entries for Loki push API, entries are grouped into streams by module and level
+func LokiEncoder(labels map[string]string) BatchEncoder {
+	return func(entries []BatchEntry) ([]byte, string, error) {
+		streams := make(map[string]*lokiStream)
+		req := lokiPushRequest{Streams: make([]*lokiStream, 0)}
+
+		for _, e := range entries {
+			key := e.Module + "\x00" + e.Level.GetLevelName()
+			stream, ok := streams[key]
+			if !ok {
+				stream = &lokiStream{Stream: make(map[string]string, len(labels)+2), Values: make([][2]string, 0)}
+				for k, v := range labels {
+					stream.Stream[k] = v
+				}
+				stream.Stream["module"] = e.Module
+				stream.Stream["level"] = e.Level.GetLevelName()
+
+				streams[key] = stream
+				req.Streams = append(req.Streams, stream)
+			}
+
+			stream.Values = append(stream.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Message})
+		}
+
+		body, err := json.Marshal(req)
+		return body, "application/json", err
+	}
+}
@@ -0,0 +1,309 @@
+package writer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
+	"github.com/mylxsw/asteria/level"
+)
+
+// ErrDisconnected is returned by NetWriter when the message is not sent because the connection is broken,
+// the message is held in backlog and will be sent after reconnecting
+var ErrDisconnected = errors.New("connection is not available, message is held in backlog")
+
+// NetWriter is a LogWriter which send logs over TCP, UDP or Unix sockets
+//
+// When the connection is broken, messages will be held in a bounded backlog,
+// and the writer will reconnect with exponential backoff on next write
+type NetWriter struct {
+	// keep 64-bit counters first to guarantee alignment for atomic operations
+	dropped uint64
+
+	network   string
+	addr      string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	framer    func(message string) []byte
+	formatter formatter.Formatter
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	backoff    time.Duration
+	nextDial   time.Time
+
+	backlog     [][]byte
+	backlogSize int
+
+	conn net.Conn
+	lock sync.Mutex
+}
+
+// NewNetWriter create a new NetWriter, network can be tcp, tcp4, tcp6, udp, udp4, udp6, unix or unixgram
+func NewNetWriter(network, addr string) *NetWriter {
+	return &NetWriter{
+		network:     network,
+		addr:        addr,
+		timeout:     5 * time.Second,
+		framer:      newlineFramer,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  30 * time.Second,
+		backlogSize: 1000,
+	}
+}
+
+// TLS enable TLS for the connection
+func (writer *NetWriter) TLS(config *tls.Config) *NetWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.tlsConfig = config
+	return writer
+}
+
+// Timeout set the timeout for dialing and writing
+func (writer *NetWriter) Timeout(timeout time.Duration) *NetWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.timeout = timeout
+	return writer
+}
+
+// Backoff set the min and max interval between reconnecting
+func (writer *NetWriter) Backoff(min, max time.Duration) *NetWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.minBackoff = min
+	writer.maxBackoff = max
+	writer.backoff = 0
+	return writer
+}
+
+// BacklogSize set max count of messages held while disconnected, the oldest message will be dropped when it is full
+func (writer *NetWriter) BacklogSize(size int) *NetWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.backlogSize = size
+	return writer
+}
+
+// Framing set how a message is framed before sending, default is a message followed by a newline
+func (writer *NetWriter) Framing(framer func(message string) []byte) *NetWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.framer = framer
+	return writer
+}
+
+// Formatter set the formatter for events, when it is set, the events received by WriteEvent are
+// formatted with it instead of using the message formatted by logger
+func (writer *NetWriter) Formatter(f formatter.Formatter) *NetWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.formatter = f
+	return writer
+}
+
+// WriteEvent send the event formatted by the formatter of writer, or the message if no formatter set
+func (writer *NetWriter) WriteEvent(evt event.Event, message []byte) error {
+	writer.lock.Lock()
+	f := writer.formatter
+	writer.lock.Unlock()
+
+	if f == nil {
+		return writer.Write(evt.Level, evt.Module, string(message))
+	}
+
+	return writer.Write(evt.Level, evt.Module, f.Format(evt))
+}
+
+// Dropped return the count of messages dropped because the backlog is full
+func (writer *NetWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+// Backlog return the count of messages waiting to be sent
+func (writer *NetWriter) Backlog() int {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return len(writer.backlog)
+}
+
+// Write send the message, the message will be held in backlog and ErrDisconnected is returned
+// if the connection is not available
+func (writer *NetWriter) Write(le level.Level, module string, message string) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.conn == nil && !time.Now().Before(writer.nextDial) {
+		_ = writer.dial()
+	}
+
+	if writer.conn != nil {
+		writer.flush()
+	}
+
+	data := writer.framer(message)
+	if writer.conn == nil || len(writer.backlog) > 0 || !writer.send(data) {
+		writer.enqueue(data)
+		return ErrDisconnected
+	}
+
+	return nil
+}
+
+// ReOpen close the connection and reconnect immediately
+func (writer *NetWriter) ReOpen() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.disconnect()
+	writer.backoff = 0
+	if err := writer.dial(); err != nil {
+		return err
+	}
+
+	writer.flush()
+	return nil
+}
+
+// Close the connection
+func (writer *NetWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.conn == nil {
+		return nil
+	}
+
+	err := writer.conn.Close()
+	writer.conn = nil
+	return err
+}
+
+func (writer *NetWriter) dial() error {
+	var conn net.Conn
+	var err error
+
+	dialer := &net.Dialer{Timeout: writer.timeout}
+	if writer.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, writer.network, writer.addr, writer.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(writer.network, writer.addr)
+	}
+
+	if err != nil {
+		writer.scheduleReconnect()
+		return err
+	}
+
+	writer.conn = conn
+	writer.backoff = 0
+	writer.nextDial = time.Time{}
+	return nil
+}
+
+// flush send all messages in backlog, stop at the first error
+func (writer *NetWriter) flush() {
+	for len(writer.backlog) > 0 {
+		if !writer.send(writer.backlog[0]) {
+			return
+		}
+
+		writer.backlog[0] = nil
+		writer.backlog = writer.backlog[1:]
+	}
+}
+
+// send write data to the connection, the connection will be closed and a reconnecting will be scheduled if failed
+func (writer *NetWriter) send(data []byte) bool {
+	if writer.timeout > 0 {
+		_ = writer.conn.SetWriteDeadline(time.Now().Add(writer.timeout))
+	}
+
+	if _, err := writer.conn.Write(data); err != nil {
+		writer.disconnect()
+		writer.scheduleReconnect()
+		return false
+	}
+
+	return true
+}
+
+func (writer *NetWriter) enqueue(data []byte) {
+	if writer.backlogSize > 0 && len(writer.backlog) >= writer.backlogSize {
+		writer.backlog[0] = nil
+		writer.backlog = writer.backlog[1:]
+		atomic.AddUint64(&writer.dropped, 1)
+	}
+
+	writer.backlog = append(writer.backlog, data)
+}
+
+func (writer *NetWriter) disconnect() {
+	if writer.conn != nil {
+		_ = writer.conn.Close()
+		writer.conn = nil
+	}
+}
+
+func (writer *NetWriter) scheduleReconnect() {
+	if writer.backoff < writer.minBackoff {
+		writer.backoff = writer.minBackoff
+	} else {
+		writer.backoff *= 2
+	}
+
+	if writer.maxBackoff > 0 && writer.backoff > writer.maxBackoff {
+		writer.backoff = writer.maxBackoff
+	}
+
+	writer.nextDial = time.Now().Add(writer.backoff)
+}
+
+func newlineFramer(message string) []byte {
+	return []byte(message + "\n")
+}
+
+// LoadClientTLSConfig create a tls config with client certificate, empty filename will be ignored
+func LoadClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no valid certificate found in " + caFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
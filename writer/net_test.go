@@ -0,0 +1,195 @@
+package writer_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveLines accept connections from listener and send every received line to the returned channel
+func serveLines(listener net.Listener) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+
+	return lines
+}
+
+func receive(t *testing.T, lines <-chan string, count int) []string {
+	received := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(time.Second):
+			t.Fatalf("expect %d lines, but only %d received", count, len(received))
+		}
+	}
+
+	return received
+}
+
+func TestNetWriter_Write(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	lines := serveLines(listener)
+
+	nw := writer.NewNetWriter("tcp", listener.Addr().String())
+	assert.NoError(t, nw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, nw.Write(level.Error, "", "Hello, error"))
+
+	assert.Equal(t, []string{"Hello, world", "Hello, error"}, receive(t, lines, 2))
+
+	assert.NoError(t, nw.ReOpen())
+	assert.NoError(t, nw.Write(level.Debug, "", "Hello, reopen"))
+	assert.Equal(t, []string{"Hello, reopen"}, receive(t, lines, 1))
+
+	assert.NoError(t, nw.Close())
+	assert.Equal(t, 0, nw.Backlog())
+}
+
+func TestNetWriter_Backlog(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	nw := writer.NewNetWriter("tcp", addr).Backoff(time.Millisecond, 10*time.Millisecond).BacklogSize(3)
+
+	for _, msg := range []string{"1", "2", "3", "4"} {
+		assert.Equal(t, writer.ErrDisconnected, nw.Write(level.Debug, "", msg))
+	}
+
+	assert.Equal(t, 3, nw.Backlog())
+	assert.Equal(t, uint64(1), nw.Dropped())
+
+	listener, err = net.Listen("tcp", addr)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	lines := serveLines(listener)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, nw.Write(level.Debug, "", "5"))
+	assert.Equal(t, []string{"2", "3", "4", "5"}, receive(t, lines, 4))
+	assert.Equal(t, 0, nw.Backlog())
+
+	assert.NoError(t, nw.Close())
+}
+
+func TestNetWriter_Failover(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	nw := writer.NewNetWriter("tcp", addr).Backoff(time.Millisecond, 10*time.Millisecond)
+	fallback := &MockWriter{}
+	fw := writer.NewFailoverWriter(nw, fallback).Threshold(1)
+
+	assert.NoError(t, fw.Write(level.Error, "", "network is down"))
+	assert.Equal(t, 1, fallback.WriteCount)
+	assert.Equal(t, uint64(1), fw.Switches())
+
+	stats := fw.Stats()
+	assert.False(t, stats[0].Healthy)
+	assert.Equal(t, uint64(1), stats[0].Errors)
+}
+
+func TestNetWriter_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	nw := writer.NewNetWriter("udp", conn.LocalAddr().String())
+	assert.NoError(t, nw.Write(level.Debug, "", "Hello, world"))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, world\n", string(buf[:n]))
+
+	assert.NoError(t, nw.Close())
+}
+
+func TestNetWriter_TLS(t *testing.T) {
+	cert, pool := generateCertificate(t)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	lines := serveLines(listener)
+
+	nw := writer.NewNetWriter("tcp", listener.Addr().String()).TLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   "127.0.0.1",
+	})
+
+	assert.NoError(t, nw.Write(level.Debug, "", "Hello, tls"))
+	assert.Equal(t, []string{"Hello, tls"}, receive(t, lines, 1))
+	assert.NoError(t, nw.Close())
+}
+
+// generateCertificate create a self-signed certificate for both server and client
+func generateCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "asteria"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	parsed, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: parsed}, pool
+}
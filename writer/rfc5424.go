@@ -0,0 +1,28 @@
+package writer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NewRFC5424Writer create a NetWriter to send RFC 5424 syslog messages, use it with formatter.RFC5424Formatter
+//
+// Messages are sent with octet-counted framing (RFC 6587) over stream sockets (tcp, unix),
+// and each message is sent as a datagram over udp or unixgram
+func NewRFC5424Writer(network, addr string) *NetWriter {
+	w := NewNetWriter(network, addr)
+	if strings.HasPrefix(network, "udp") || network == "unixgram" {
+		return w.Framing(datagramFramer)
+	}
+
+	return w.Framing(OctetCountingFramer)
+}
+
+// OctetCountingFramer frame the message as MSG-LEN SP SYSLOG-MSG
+func OctetCountingFramer(message string) []byte {
+	return []byte(strconv.Itoa(len(message)) + " " + message)
+}
+
+func datagramFramer(message string) []byte {
+	return []byte(message)
+}
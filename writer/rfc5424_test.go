@@ -0,0 +1,74 @@
+package writer_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOctetCountingFramer(t *testing.T) {
+	assert.Equal(t, "12 Hello, world", string(writer.OctetCountingFramer("Hello, world")))
+}
+
+func TestRFC5424Writer_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	frames := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				return
+			}
+
+			frames <- string(buf)
+		}
+	}()
+
+	w := writer.NewRFC5424Writer("tcp", listener.Addr().String())
+	assert.NoError(t, w.Write(level.Debug, "", "<135>1 - - - - - - Hello\nworld"))
+	assert.NoError(t, w.Write(level.Debug, "", "<135>1 - - - - - - Hello"))
+
+	assert.Equal(t, []string{"<135>1 - - - - - - Hello\nworld", "<135>1 - - - - - - Hello"}, receive(t, frames, 2))
+	assert.NoError(t, w.Close())
+}
+
+func TestRFC5424Writer_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	w := writer.NewRFC5424Writer("udp", conn.LocalAddr().String())
+	assert.NoError(t, w.Write(level.Debug, "", "<135>1 - - - - - - Hello"))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "<135>1 - - - - - - Hello", string(buf[:n]))
+
+	assert.NoError(t, w.Close())
+}
@@ -0,0 +1,307 @@
+package writer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// SizeRotatingFileWriter is a LogWriter which rotate the log file once it passes the max size
+type SizeRotatingFileWriter struct {
+	writer     *FileWriter
+	filename   string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+
+	// size of current log file, -1 means unknown
+	size int64
+
+	lock      sync.Mutex
+	cleanLock sync.Mutex
+	bg        sync.WaitGroup
+}
+
+// NewSizeRotatingFileWriter create a new SizeRotatingFileWriter, maxSize is in bytes
+func NewSizeRotatingFileWriter(filename string, maxSize int64) *SizeRotatingFileWriter {
+	return &SizeRotatingFileWriter{
+		writer:   NewDefaultFileWriter(filename),
+		filename: filename,
+		maxSize:  maxSize,
+		size:     -1,
+	}
+}
+
+// MaxBackups set the max count of backups to keep, 0 means no limit
+func (writer *SizeRotatingFileWriter) MaxBackups(n int) *SizeRotatingFileWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.maxBackups = n
+	return writer
+}
+
+// MaxAge set the max age of backups to keep, 0 means no limit
+func (writer *SizeRotatingFileWriter) MaxAge(d time.Duration) *SizeRotatingFileWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.maxAge = d
+	return writer
+}
+
+// Compress set whether compress the rotated files using gzip
+func (writer *SizeRotatingFileWriter) Compress(enable bool) *SizeRotatingFileWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.compress = enable
+	return writer
+}
+
+// Write the message to file, rotate it if the file passes the max size
+func (writer *SizeRotatingFileWriter) Write(le level.Level, module string, message string) error {
+	return writer.write(int64(len(message)+1), func() error {
+		return writer.writer.Write(le, module, message)
+	})
+}
+
+// WriteBytes write the message without converting it to string, rotate the file if it passes the max size
+func (writer *SizeRotatingFileWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	return writer.write(int64(len(message)+1), func() error {
+		return writer.writer.WriteBytes(le, module, message)
+	})
+}
+
+// write call fn to write length bytes to file, rotate it before writing if the file will pass the max size
+func (writer *SizeRotatingFileWriter) write(length int64, fn func() error) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.size < 0 {
+		writer.size = 0
+		if stat, err := os.Stat(writer.filename); err == nil {
+			writer.size = stat.Size()
+		}
+	}
+
+	if writer.maxSize > 0 && writer.size > 0 && writer.size+length > writer.maxSize {
+		if err := writer.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	writer.size += length
+	return nil
+}
+
+// Rotate close the current log file, rename it to a backup and start a new file
+func (writer *SizeRotatingFileWriter) Rotate() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.rotate()
+}
+
+func (writer *SizeRotatingFileWriter) rotate() error {
+	if err := writer.writer.Close(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(writer.filename); err == nil {
+		if err := os.Rename(writer.filename, writer.backupName(time.Now())); err != nil {
+			return err
+		}
+	}
+
+	writer.size = 0
+
+	compress, maxBackups, maxAge := writer.compress, writer.maxBackups, writer.maxAge
+	writer.bg.Add(1)
+	go func() {
+		defer writer.bg.Done()
+		writer.cleanBackups(compress, maxBackups, maxAge)
+	}()
+
+	return nil
+}
+
+// backupName return a backup filename like app-2006-01-02T15-04-05.000.log for app.log
+func (writer *SizeRotatingFileWriter) backupName(t time.Time) string {
+	prefix, ext := writer.backupPrefixAndExt()
+
+	name := prefix + t.Format(backupTimeFormat) + ext
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s%s.%d%s", prefix, t.Format(backupTimeFormat), i, ext)
+	}
+
+	return name
+}
+
+func (writer *SizeRotatingFileWriter) backupPrefixAndExt() (string, string) {
+	ext := filepath.Ext(writer.filename)
+	return strings.TrimSuffix(writer.filename, ext) + "-", ext
+}
+
+type backupFile struct {
+	path    string
+	modTime time.Time
+}
+
+// Backups return all backup files of the log file, sorted from newest to oldest
+func (writer *SizeRotatingFileWriter) Backups() ([]string, error) {
+	backups, err := writer.listBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, len(backups))
+	for i, b := range backups {
+		files[i] = b.path
+	}
+
+	return files, nil
+}
+
+func (writer *SizeRotatingFileWriter) listBackups() ([]backupFile, error) {
+	prefix, ext := writer.backupPrefixAndExt()
+
+	matches, err := filepath.Glob(escapeGlob(prefix) + "*" + escapeGlob(ext) + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]backupFile, 0, len(matches))
+	for _, m := range matches {
+		name := strings.TrimSuffix(m, ".gz")
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if len(ts) < len(backupTimeFormat) {
+			continue
+		}
+
+		t, err := time.ParseInLocation(backupTimeFormat, ts[:len(backupTimeFormat)], time.Local)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backupFile{path: m, modTime: t})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		if backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].path > backups[j].path
+		}
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+
+	return backups, nil
+}
+
+func (writer *SizeRotatingFileWriter) cleanBackups(compress bool, maxBackups int, maxAge time.Duration) {
+	writer.cleanLock.Lock()
+	defer writer.cleanLock.Unlock()
+
+	backups, err := writer.listBackups()
+	if err != nil {
+		return
+	}
+
+	expiredAt := time.Now().Add(-maxAge)
+	for i, b := range backups {
+		if (maxBackups > 0 && i >= maxBackups) || (maxAge > 0 && b.modTime.Before(expiredAt)) {
+			_ = os.Remove(b.path)
+			continue
+		}
+
+		if compress && !strings.HasSuffix(b.path, ".gz") {
+			_ = gzipFile(b.path)
+		}
+	}
+}
+
+// ReOpen reopen the log file
+func (writer *SizeRotatingFileWriter) ReOpen() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.size = -1
+	return writer.writer.ReOpen()
+}
+
+// Close the log file and wait for background compression finished
+func (writer *SizeRotatingFileWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.bg.Wait()
+
+	writer.size = -1
+	return writer.writer.Close()
+}
+
+func gzipFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	stat, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(src+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, stat.Mode())
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(src + ".gz")
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(src + ".gz")
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		_ = os.Remove(src + ".gz")
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+func escapeGlob(pattern string) string {
+	replacer := strings.NewReplacer("*", "\\*", "?", "\\?", "[", "\\[", "]", "\\]")
+	return replacer.Replace(pattern)
+}
@@ -0,0 +1,130 @@
+package writer_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeRotatingFileWriter_Write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.log")
+	fw := writer.NewSizeRotatingFileWriter(filename, 20)
+
+	// each line is 13 bytes, so every line will be written to a new file
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	}
+	assert.NoError(t, fw.Close())
+
+	backups, err := fw.Backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+
+	rs, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, world\n", string(rs))
+
+	// the existing file size should be respected after reopen
+	fw = writer.NewSizeRotatingFileWriter(filename, 30)
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, fw.Close())
+
+	backups, err = fw.Backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 3)
+}
+
+func TestSizeRotatingFileWriter_Retention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.log")
+	fw := writer.NewSizeRotatingFileWriter(filename, 20).MaxBackups(2).Compress(true)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	}
+	assert.NoError(t, fw.Close())
+
+	backups, err := fw.Backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+
+	for _, b := range backups {
+		assert.True(t, strings.HasSuffix(b, ".log.gz"))
+
+		f, err := os.Open(b)
+		assert.NoError(t, err)
+
+		gz, err := gzip.NewReader(f)
+		assert.NoError(t, err)
+
+		rs, err := ioutil.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello, world\n", string(rs))
+
+		_ = f.Close()
+	}
+}
+
+func TestSizeRotatingFileWriter_MaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expired := filepath.Join(dir, "test-"+time.Now().Add(-2*time.Hour).Format("2006-01-02T15-04-05.000")+".log")
+	assert.NoError(t, ioutil.WriteFile(expired, []byte("expired\n"), 0666))
+
+	filename := filepath.Join(dir, "test.log")
+	fw := writer.NewSizeRotatingFileWriter(filename, 1024).MaxAge(time.Hour)
+
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, fw.Rotate())
+	assert.NoError(t, fw.Close())
+
+	backups, err := fw.Backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.NotEqual(t, expired, backups[0])
+}
+
+func TestSizeRotatingFileWriter_WriteBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.log")
+	fw := writer.NewSizeRotatingFileWriter(filename, 30)
+
+	// the size written by WriteBytes is counted too
+	assert.NoError(t, fw.WriteBytes(level.Debug, "", []byte("Hello, world")))
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, fw.WriteBytes(level.Debug, "", []byte("Hello, world")))
+	assert.NoError(t, fw.Close())
+
+	backups, err := fw.Backups()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+
+	rs, err := ioutil.ReadFile(backups[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, world\nHello, world\n", string(rs))
+
+	rs, err = ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, world\n", string(rs))
+}
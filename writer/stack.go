@@ -1,14 +1,61 @@
 package writer
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mylxsw/asteria/event"
+	"github.com/mylxsw/asteria/formatter"
 	"github.com/mylxsw/asteria/level"
 )
 
+// ErrStackWriterBusy is reported for a writer skipped because its last call timed out and is still running
+var ErrStackWriterBusy = errors.New("writer is busy with a timed out call")
+
 type StackMatchFn func(le level.Level, module string, message string) bool
 
+// StackMode decide how StackWriter call its writers
+type StackMode int
+
+const (
+	// StackSequential call writers one after another and stop at the first error, it is the default mode
+	StackSequential StackMode = iota
+	// StackAll call all writers one after another, errors are aggregated
+	StackAll
+	// StackParallel call all writers in parallel, errors are aggregated
+	StackParallel
+)
+
+// StackFailure is the error of a writer in StackWriter
+type StackFailure struct {
+	Name string
+	Err  error
+}
+
+// StackError is the aggregated error of StackWriter
+type StackError struct {
+	Failures []StackFailure
+}
+
+func (e *StackError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		messages[i] = fmt.Sprintf("%s: %v", f.Name, f.Err)
+	}
+
+	return fmt.Sprintf("%d writers failed: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
 type stackWriter struct {
+	name   string
 	writer Writer
 	fn     StackMatchFn
+	// hung is the number of timed out calls still running
+	hung *int32
 }
 
 func (writer stackWriter) canWrite(le level.Level, module string, message string) bool {
@@ -17,6 +64,10 @@ func (writer stackWriter) canWrite(le level.Level, module string, message string
 
 type StackWriter struct {
 	writers []stackWriter
+	mode    StackMode
+	timeout time.Duration
+
+	lock sync.RWMutex
 }
 
 // NewStackWriter create a new stack writer
@@ -24,18 +75,60 @@ func NewStackWriter() *StackWriter {
 	return &StackWriter{writers: make([]stackWriter, 0)}
 }
 
+// Mode set how writers are called, default is StackSequential
+func (writer *StackWriter) Mode(mode StackMode) *StackWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.mode = mode
+	return writer
+}
+
+// Timeout set the timeout for each writer in StackParallel mode, 0 means no timeout
+//
+// When a writer timed out, it is reported as failed, but the writing is not cancelled, the writer
+// is skipped (reported as ErrStackWriterBusy) until the timed out call returned, so that a hung
+// writer holds at most one goroutine
+func (writer *StackWriter) Timeout(d time.Duration) *StackWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.timeout = d
+	return writer
+}
+
 // Push add a writer to stacks
 func (writer *StackWriter) Push(w Writer, fn StackMatchFn) {
+	writer.PushNamed("", w, fn)
+}
+
+// PushWithLevels add a writer with only specified levels enabled
+// if no levels specified, we will use all
+func (writer *StackWriter) PushWithLevels(w Writer, levels ...level.Level) {
+	writer.PushNamedWithLevels("", w, levels...)
+}
+
+// PushWithFormatter add a writer which format the event with its own formatter, only specified levels enabled
+func (writer *StackWriter) PushWithFormatter(w Writer, f formatter.Formatter, levels ...level.Level) {
+	writer.PushNamedWithLevels("", NewFormattedWriter(w, f), levels...)
+}
+
+// PushNamed add a writer with name to stacks, the name is used in errors, Remove and Replace
+func (writer *StackWriter) PushNamed(name string, w Writer, fn StackMatchFn) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
 	writer.writers = append(writer.writers, stackWriter{
+		name:   name,
 		writer: w,
 		fn:     fn,
+		hung:   new(int32),
 	})
 }
 
-// PushWithLevels add a writer with only specified levels enabled
-// if no levels specified, we will use all
-func (writer *StackWriter) PushWithLevels(w Writer, levels ...level.Level) {
-	writer.Push(w, func(le level.Level, module string, message string) bool {
+// PushNamedWithLevels add a writer with name, only specified levels enabled
+func (writer *StackWriter) PushNamedWithLevels(name string, w Writer, levels ...level.Level) {
+	writer.PushNamed(name, w, func(le level.Level, module string, message string) bool {
 		if len(levels) == 0 {
 			return true
 		}
@@ -50,36 +143,202 @@ func (writer *StackWriter) PushWithLevels(w Writer, levels ...level.Level) {
 	})
 }
 
-func (writer *StackWriter) Write(le level.Level, module string, message string) error {
+// Remove the writers with the name, return false if not found or the name is empty
+// (the writers added by Push have no name)
+//
+// The writer removed is not closed
+func (writer *StackWriter) Remove(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writers := make([]stackWriter, 0, len(writer.writers))
 	for _, w := range writer.writers {
-		if !w.canWrite(le, module, message) {
-			continue
+		if w.name != name {
+			writers = append(writers, w)
 		}
+	}
+
+	removed := len(writers) != len(writer.writers)
+	writer.writers = writers
+
+	return removed
+}
 
-		if err := w.writer.Write(le, module, message); err != nil {
-			return err
+// Replace the writers with the name, the match function is kept, return false if not found or the name is empty
+//
+// The writer replaced is not closed
+func (writer *StackWriter) Replace(name string, w Writer) bool {
+	if name == "" {
+		return false
+	}
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	replaced := false
+	writers := make([]stackWriter, len(writer.writers))
+	for i, sw := range writer.writers {
+		if sw.name == name {
+			sw.writer = w
+			sw.hung = new(int32)
+			replaced = true
 		}
+
+		writers[i] = sw
+	}
+
+	writer.writers = writers
+
+	return replaced
+}
+
+func (writer *StackWriter) Write(le level.Level, module string, message string) error {
+	return writer.each(func(w stackWriter) bool {
+		return w.canWrite(le, module, message)
+	}, func(w Writer) error {
+		return w.Write(le, module, message)
+	})
+}
+
+// WriteBytes write the message to all writers matched, the writers implement BytesWriter receive the
+// message without converting to string
+func (writer *StackWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	message = writer.retain(message)
+	msg := string(message)
+	return writer.each(func(w stackWriter) bool {
+		return w.canWrite(le, module, msg)
+	}, func(w Writer) error {
+		return writeBytes(w, le, module, message)
+	})
+}
+
+// WriteEvent write the event to all writers matched, the writers implement EventWriter
+// (such as FormattedWriter) receive the event, others receive the message
+func (writer *StackWriter) WriteEvent(evt event.Event, message []byte) error {
+	message = writer.retain(message)
+	msg := string(message)
+	return writer.each(func(w stackWriter) bool {
+		return w.canWrite(evt.Level, evt.Module, msg)
+	}, func(w Writer) error {
+		return writeEvent(w, evt, message)
+	})
+}
+
+// retain return a copy of message if the writers may keep running after a timeout, the message
+// is only valid during the call, the buffer is reused by the logger after returned
+func (writer *StackWriter) retain(message []byte) []byte {
+	writer.lock.RLock()
+	detached := writer.mode == StackParallel && writer.timeout > 0
+	writer.lock.RUnlock()
+
+	if detached {
+		return append([]byte(nil), message...)
 	}
 
-	return nil
+	return message
 }
 
 func (writer *StackWriter) ReOpen() error {
-	for _, w := range writer.writers {
-		if err := w.writer.ReOpen(); err != nil {
-			return err
+	return writer.each(nil, func(w Writer) error {
+		return w.ReOpen()
+	})
+}
+
+func (writer *StackWriter) Close() error {
+	return writer.each(nil, func(w Writer) error {
+		return w.Close()
+	})
+}
+
+// each call fn for the writers matched according to the mode
+func (writer *StackWriter) each(match func(w stackWriter) bool, fn func(w Writer) error) error {
+	writer.lock.RLock()
+	writers, mode, timeout := writer.writers, writer.mode, writer.timeout
+	writer.lock.RUnlock()
+
+	errs := make([]error, len(writers))
+
+	var wg sync.WaitGroup
+	for i, w := range writers {
+		if match != nil && !match(w) {
+			continue
+		}
+
+		switch mode {
+		case StackParallel:
+			wg.Add(1)
+			go func(i int, w stackWriter) {
+				defer wg.Done()
+				errs[i] = w.callWithTimeout(fn, timeout)
+			}(i, w)
+		case StackAll:
+			errs[i] = fn(w.writer)
+		default:
+			if err := fn(w.writer); err != nil {
+				return err
+			}
 		}
 	}
 
-	return nil
-}
+	wg.Wait()
 
-func (writer *StackWriter) Close() error {
-	for _, w := range writer.writers {
-		if err := w.writer.Close(); err != nil {
-			return err
+	var failures []StackFailure
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		name := writers[i].name
+		if name == "" {
+			name = fmt.Sprintf("#%d(%T)", i, writers[i].writer)
 		}
+
+		failures = append(failures, StackFailure{Name: name, Err: err})
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &StackError{Failures: failures}
+}
+
+// callWithTimeout call fn for the writer, a timed out call is tracked until it returned
+func (w stackWriter) callWithTimeout(fn func(w Writer) error, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fn(w.writer)
+	}
+
+	if atomic.LoadInt32(w.hung) > 0 {
+		return ErrStackWriterBusy
 	}
 
-	return nil
+	// state of the call: 0 running, 1 returned, 2 timed out
+	var state int32
+	res := make(chan error, 1)
+	go func() {
+		err := fn(w.writer)
+		if !atomic.CompareAndSwapInt32(&state, 0, 1) {
+			atomic.AddInt32(w.hung, -1)
+		}
+		res <- err
+	}()
+
+	select {
+	case err := <-res:
+		return err
+	case <-time.After(timeout):
+		atomic.AddInt32(w.hung, 1)
+		if !atomic.CompareAndSwapInt32(&state, 0, 2) {
+			// returned just now
+			atomic.AddInt32(w.hung, -1)
+			return <-res
+		}
+
+		return fmt.Errorf("timeout after %s", timeout)
+	}
 }
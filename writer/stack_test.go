@@ -1,7 +1,10 @@
 package writer_test
 
 import (
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mylxsw/asteria/level"
 	"github.com/mylxsw/asteria/writer"
@@ -97,3 +100,182 @@ func TestStackWriter_Distribute(t *testing.T) {
 	assert.Equal(t, 2, m2.WriteCount)
 	assert.Equal(t, 8, m3.WriteCount)
 }
+
+type FailingWriter struct {
+	Err   error
+	Delay time.Duration
+	MockWriter
+}
+
+func (m *FailingWriter) Write(le level.Level, module string, message string) error {
+	time.Sleep(m.Delay)
+	_ = m.MockWriter.Write(le, module, message)
+	return m.Err
+}
+
+func (m *FailingWriter) ReOpen() error {
+	_ = m.MockWriter.ReOpen()
+	return m.Err
+}
+
+func (m *FailingWriter) Close() error {
+	_ = m.MockWriter.Close()
+	return m.Err
+}
+
+func TestStackWriter_Mode(t *testing.T) {
+	m1 := &FailingWriter{Err: errors.New("disk full")}
+	m2 := &MockWriter{}
+	m3 := &FailingWriter{Err: errors.New("connection refused")}
+
+	stack := writer.NewStackWriter()
+	stack.PushNamedWithLevels("file", m1)
+	stack.PushWithLevels(m2)
+	stack.PushNamedWithLevels("syslog", m3)
+
+	// stop at the first error by default
+	assert.EqualError(t, stack.Write(level.Debug, "", "hello"), "disk full")
+	assert.Equal(t, 0, m2.WriteCount)
+
+	for _, mode := range []writer.StackMode{writer.StackAll, writer.StackParallel} {
+		m2.WriteCount, m3.WriteCount = 0, 0
+
+		stack.Mode(mode)
+		err := stack.Write(level.Debug, "", "hello")
+		assert.EqualError(t, err, "2 writers failed: file: disk full; syslog: connection refused")
+		assert.Equal(t, 1, m2.WriteCount)
+		assert.Equal(t, 1, m3.WriteCount)
+
+		stackErr, ok := err.(*writer.StackError)
+		assert.True(t, ok)
+		assert.Equal(t, "syslog", stackErr.Failures[1].Name)
+
+		assert.Error(t, stack.ReOpen())
+		assert.Error(t, stack.Close())
+		assert.Equal(t, m2.ReOpenCount, m3.ReOpenCount)
+		assert.Equal(t, m2.CloseCount, m3.CloseCount)
+	}
+}
+
+func TestStackWriter_Timeout(t *testing.T) {
+	slow := &FailingWriter{Delay: 200 * time.Millisecond}
+	fast := &MockWriter{}
+
+	stack := writer.NewStackWriter().Mode(writer.StackParallel).Timeout(20 * time.Millisecond)
+	stack.PushWithLevels(slow)
+	stack.PushWithLevels(fast)
+
+	startTime := time.Now()
+	err := stack.Write(level.Debug, "", "hello")
+	assert.True(t, time.Since(startTime) < 200*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "#0(*writer_test.FailingWriter): timeout after 20ms")
+	assert.Equal(t, 1, fast.WriteCount)
+}
+
+type slowBytesWriter struct {
+	MockWriter
+	delay    time.Duration
+	lock     sync.Mutex
+	messages []string
+}
+
+func (m *slowBytesWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	time.Sleep(m.delay)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.messages = append(m.messages, string(message))
+	return nil
+}
+
+func (m *slowBytesWriter) Messages() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]string(nil), m.messages...)
+}
+
+func TestStackWriter_TimeoutMessageReused(t *testing.T) {
+	slow := &slowBytesWriter{delay: 50 * time.Millisecond}
+
+	stack := writer.NewStackWriter().Mode(writer.StackParallel).Timeout(5 * time.Millisecond)
+	stack.PushWithLevels(slow)
+
+	buf := []byte("hello, world")
+	assert.Error(t, stack.WriteBytes(level.Debug, "", buf))
+
+	// the logger reuse the buffer once WriteBytes returned
+	copy(buf, "HELLO, WORLD")
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"hello, world"}, slow.Messages())
+}
+
+func TestStackWriter_TimeoutBusy(t *testing.T) {
+	slow := &slowBytesWriter{delay: 50 * time.Millisecond}
+	fast := &slowBytesWriter{}
+
+	stack := writer.NewStackWriter().Mode(writer.StackParallel).Timeout(5 * time.Millisecond)
+	stack.PushNamedWithLevels("slow", slow)
+	stack.PushNamedWithLevels("fast", fast)
+
+	assert.EqualError(t, stack.WriteBytes(level.Debug, "", []byte("first")), "1 writers failed: slow: timeout after 5ms")
+
+	// the slow writer is skipped until the timed out call returned
+	err := stack.WriteBytes(level.Debug, "", []byte("second"))
+	assert.Error(t, err)
+	assert.Equal(t, writer.ErrStackWriterBusy, err.(*writer.StackError).Failures[0].Err)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"first"}, slow.Messages())
+	assert.Equal(t, []string{"first", "second"}, fast.Messages())
+
+	assert.EqualError(t, stack.WriteBytes(level.Debug, "", []byte("third")), "1 writers failed: slow: timeout after 5ms")
+}
+
+func TestStackWriter_RemoveReplace(t *testing.T) {
+	m1 := &MockWriter{}
+	m2 := &MockWriter{}
+	m3 := &MockWriter{}
+
+	stack := writer.NewStackWriter()
+	stack.PushNamedWithLevels("error", m1, level.Error)
+	stack.PushNamedWithLevels("all", m2)
+
+	assert.True(t, stack.Replace("error", m3))
+	assert.False(t, stack.Replace("not-exist", m3))
+
+	_ = stack.Write(level.Error, "", "hello")
+	_ = stack.Write(level.Debug, "", "hello")
+	assert.Equal(t, 0, m1.WriteCount)
+	assert.Equal(t, 1, m3.WriteCount)
+	assert.Equal(t, 2, m2.WriteCount)
+
+	assert.True(t, stack.Remove("all"))
+	assert.False(t, stack.Remove("all"))
+
+	_ = stack.Write(level.Error, "", "hello")
+	assert.Equal(t, 2, m2.WriteCount)
+	assert.Equal(t, 2, m3.WriteCount)
+}
+
+func TestStackWriter_RemoveReplaceEmptyName(t *testing.T) {
+	m1 := &MockWriter{}
+	m2 := &MockWriter{}
+	m3 := &MockWriter{}
+
+	stack := writer.NewStackWriter()
+	stack.PushWithLevels(m1)
+	stack.PushWithLevels(m2, level.Error)
+
+	// the writers added by Push have no name, they can not be replaced or removed by name
+	assert.False(t, stack.Replace("", m3))
+	assert.False(t, stack.Remove(""))
+
+	_ = stack.Write(level.Error, "", "hello")
+	assert.Equal(t, 1, m1.WriteCount)
+	assert.Equal(t, 1, m2.WriteCount)
+	assert.Equal(t, 0, m3.WriteCount)
+}
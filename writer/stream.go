@@ -25,6 +25,11 @@ func (writer *StreamWriter) Write(le level.Level, module string, message string)
 	return err
 }
 
+// WriteBytes write the message without converting it to string
+func (writer *StreamWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	return writeLine(writer.w, message)
+}
+
 func (writer *StreamWriter) ReOpen() error {
 	return nil
 }
@@ -1,6 +1,7 @@
 package writer_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/mylxsw/asteria/level"
@@ -29,3 +30,12 @@ func TestStreamWriter_Write(t *testing.T) {
 	assert.NoError(t, sw.Close())
 	assert.NoError(t, sw.ReOpen())
 }
+
+func TestStreamWriter_WriteBytes(t *testing.T) {
+	var buf bytes.Buffer
+	sw := writer.NewStreamWriter(&buf)
+
+	assert.NoError(t, sw.WriteBytes(level.Debug, "", []byte("Hello, world")))
+	assert.NoError(t, sw.Write(level.Debug, "", "Yes, you are"))
+	assert.Equal(t, "Hello, world\nYes, you are\n", buf.String())
+}
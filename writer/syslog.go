@@ -82,6 +82,10 @@ func (w *SyslogWriter) Close() error {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 
+	if w.syslogWriter == nil {
+		return nil
+	}
+
 	err := w.syslogWriter.Close()
 	w.syslogWriter = nil
 	return err
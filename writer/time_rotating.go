@@ -0,0 +1,173 @@
+package writer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/misc"
+)
+
+// TimeRotatingFileWriter is a RotatingFileWriter which generate filename with strftime-style pattern, such as app-%Y%m%d%H.log
+type TimeRotatingFileWriter struct {
+	*RotatingFileWriter
+
+	pattern string
+	clock   func() time.Time
+	symlink string
+	maxAge  time.Duration
+	current string
+
+	lock      sync.Mutex
+	cleanLock sync.Mutex
+	bg        sync.WaitGroup
+}
+
+// NewTimeRotatingFileWriter create a new TimeRotatingFileWriter
+func NewTimeRotatingFileWriter(ctx context.Context, pattern string) *TimeRotatingFileWriter {
+	wr := &TimeRotatingFileWriter{pattern: pattern, clock: time.Now}
+	wr.RotatingFileWriter = NewDefaultRotatingFileWriter(ctx, func(le level.Level, module string) string {
+		return wr.Filename()
+	})
+
+	return wr
+}
+
+// WithClock set the clock used to generate filename
+func (writer *TimeRotatingFileWriter) WithClock(clock func() time.Time) *TimeRotatingFileWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.clock = clock
+	return writer
+}
+
+// Symlink set a symlink which always point to the active log file, such as /var/log/current
+func (writer *TimeRotatingFileWriter) Symlink(symlink string) *TimeRotatingFileWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.symlink = symlink
+	return writer
+}
+
+// MaxAge set the max age of log files to keep, 0 means no limit
+//
+// The age of a file is the time in its filename according to the clock, such as the file app-20190710.log
+// is expired after 2019-07-10 00:00:00 + maxAge, the pattern should contain the year (%Y or %y)
+func (writer *TimeRotatingFileWriter) MaxAge(d time.Duration) *TimeRotatingFileWriter {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.maxAge = d
+	return writer
+}
+
+// Filename return the log filename for now
+func (writer *TimeRotatingFileWriter) Filename() string {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return misc.Strftime(writer.pattern, writer.clock())
+}
+
+// Write the message to the log file for now
+func (writer *TimeRotatingFileWriter) Write(le level.Level, module string, message string) error {
+	filename := writer.Filename()
+	if err := writer.getWriter(filename).Write(le, module, message); err != nil {
+		return err
+	}
+
+	return writer.written(filename)
+}
+
+// WriteBytes write the message to the log file for now without converting it to string
+func (writer *TimeRotatingFileWriter) WriteBytes(le level.Level, module string, message []byte) error {
+	filename := writer.Filename()
+	if err := writer.getWriter(filename).WriteBytes(le, module, message); err != nil {
+		return err
+	}
+
+	return writer.written(filename)
+}
+
+// written update the symlink and remove expired files when switched to a new file
+func (writer *TimeRotatingFileWriter) written(filename string) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.current != filename {
+		writer.current = filename
+		if writer.symlink != "" {
+			if err := updateSymlink(filename, writer.symlink); err != nil {
+				return err
+			}
+		}
+
+		if writer.maxAge > 0 {
+			now, maxAge := writer.clock(), writer.maxAge
+			writer.bg.Add(1)
+			go func() {
+				defer writer.bg.Done()
+				writer.removeExpired(filename, now.Add(-maxAge), now.Location())
+			}()
+		}
+	}
+
+	return nil
+}
+
+// Close all opened files and wait for background cleaning finished
+func (writer *TimeRotatingFileWriter) Close() error {
+	writer.bg.Wait()
+	return writer.RotatingFileWriter.Close()
+}
+
+// removeExpired remove the files generated by pattern which are older than expiredAt,
+// the files only matching the glob but not the pattern are kept
+func (writer *TimeRotatingFileWriter) removeExpired(current string, expiredAt time.Time, loc *time.Location) {
+	writer.cleanLock.Lock()
+	defer writer.cleanLock.Unlock()
+
+	matches, err := filepath.Glob(misc.StrftimeGlob(writer.pattern))
+	if err != nil {
+		return
+	}
+
+	for _, m := range matches {
+		if m == current || m == writer.symlink {
+			continue
+		}
+
+		createdAt, err := misc.StrftimeParse(writer.pattern, m, loc)
+		if err != nil || !createdAt.Before(expiredAt) {
+			continue
+		}
+
+		stat, err := os.Lstat(m)
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+
+		writer.RotatingFileWriter.closeFile(m)
+		_ = os.Remove(m)
+	}
+}
+
+// updateSymlink point the symlink to target atomically
+func updateSymlink(target, symlink string) error {
+	if rel, err := filepath.Rel(filepath.Dir(symlink), target); err == nil {
+		target = rel
+	}
+
+	tmp := symlink + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, symlink)
+}
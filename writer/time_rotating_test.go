@@ -0,0 +1,120 @@
+package writer_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeRotatingFileWriter_Write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2019, 7, 17, 16, 58, 24, 0, time.Local)
+	symlink := filepath.Join(dir, "current")
+
+	fw := writer.NewTimeRotatingFileWriter(context.TODO(), filepath.Join(dir, "app-%Y%m%d%H.log")).
+		WithClock(func() time.Time { return now }).
+		Symlink(symlink)
+
+	assert.Equal(t, filepath.Join(dir, "app-2019071716.log"), fw.Filename())
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+
+	target, err := os.Readlink(symlink)
+	assert.NoError(t, err)
+	assert.Equal(t, "app-2019071716.log", target)
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+
+	target, err = os.Readlink(symlink)
+	assert.NoError(t, err)
+	assert.Equal(t, "app-2019071717.log", target)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "app-2019071716.log"),
+		filepath.Join(dir, "app-2019071717.log"),
+	}, fw.GetOpenedFiles())
+
+	assert.NoError(t, fw.Close())
+
+	rs, err := ioutil.ReadFile(symlink)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, world\nHello, world\n", string(rs))
+}
+
+func TestTimeRotatingFileWriter_MaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expired := filepath.Join(dir, "app-20190710.log")
+	assert.NoError(t, ioutil.WriteFile(expired, []byte("expired\n"), 0666))
+
+	kept := filepath.Join(dir, "app-20190711.log")
+	assert.NoError(t, ioutil.WriteFile(kept, []byte("kept\n"), 0666))
+
+	// the files not generated by the pattern are kept, whatever their mtime is
+	siblings := []string{filepath.Join(dir, "app-error.log"), filepath.Join(dir, "app-backup.log"), filepath.Join(dir, "app-2019071.log")}
+	for _, sibling := range siblings {
+		assert.NoError(t, ioutil.WriteFile(sibling, []byte("sibling\n"), 0666))
+		assert.NoError(t, os.Chtimes(sibling, time.Now().Add(-72*time.Hour), time.Now().Add(-72*time.Hour)))
+	}
+
+	fw := writer.NewTimeRotatingFileWriter(context.TODO(), filepath.Join(dir, "app-%Y%m%d.log")).
+		WithClock(func() time.Time { return time.Date(2019, 7, 12, 0, 0, 0, 0, time.UTC) }).
+		MaxAge(36 * time.Hour)
+
+	assert.NoError(t, fw.Write(level.Debug, "", "Hello, world"))
+	assert.NoError(t, fw.Close())
+
+	_, err = os.Stat(expired)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(kept)
+	assert.NoError(t, err)
+
+	for _, sibling := range siblings {
+		_, err = os.Stat(sibling)
+		assert.NoError(t, err)
+	}
+
+	_, err = os.Stat(fw.Filename())
+	assert.NoError(t, err)
+}
+
+func TestTimeRotatingFileWriter_WriteBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asteria")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2019, 7, 17, 16, 58, 24, 0, time.Local)
+	symlink := filepath.Join(dir, "current")
+
+	fw := writer.NewTimeRotatingFileWriter(context.TODO(), filepath.Join(dir, "app-%Y%m%d%H.log")).
+		WithClock(func() time.Time { return now }).
+		Symlink(symlink)
+
+	assert.NoError(t, fw.WriteBytes(level.Debug, "", []byte("Hello, world")))
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, fw.WriteBytes(level.Debug, "", []byte("Hello, world")))
+	assert.NoError(t, fw.Close())
+
+	target, err := os.Readlink(symlink)
+	assert.NoError(t, err)
+	assert.Equal(t, "app-2019071717.log", target)
+
+	rs, err := ioutil.ReadFile(symlink)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, world\n", string(rs))
+}
@@ -1,7 +1,10 @@
 package writer
 
 import (
+	"io"
+
 	"github.com/mylxsw/asteria/level"
+	"github.com/mylxsw/asteria/misc"
 )
 
 // Writer 日志输出接口
@@ -10,3 +13,31 @@ type Writer interface {
 	ReOpen() error
 	Close() error
 }
+
+// BytesWriter is a Writer which accept the message as byte slice, loggers call WriteBytes
+// with the pooled buffer the message formatted in, so that no string is allocated
+//
+// The message is only valid during the call, copy it if the writer holds it after returning
+type BytesWriter interface {
+	Writer
+	WriteBytes(le level.Level, module string, message []byte) error
+}
+
+// writeBytes write the message to w, WriteBytes is used if w is a BytesWriter
+func writeBytes(w Writer, le level.Level, module string, message []byte) error {
+	if bw, ok := w.(BytesWriter); ok {
+		return bw.WriteBytes(le, module, message)
+	}
+
+	return w.Write(le, module, string(message))
+}
+
+// writeLine write the message and a new line to w in one call, using a pooled buffer
+func writeLine(w io.Writer, message []byte) error {
+	buf := misc.GetBuffer()
+	defer misc.PutBuffer(buf)
+
+	*buf = append(append(*buf, message...), '\n')
+	_, err := w.Write(*buf)
+	return err
+}